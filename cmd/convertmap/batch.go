@@ -0,0 +1,203 @@
+package convertmap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"xgtool/internal/atlas"
+	"xgtool/internal/tmx"
+	"xgtool/internal/worker"
+	"xgtool/pkg"
+
+	"github.com/rs/zerolog/log"
+	"github.com/schollz/progressbar/v3"
+)
+
+var errConvertFailed = errors.New("convert failed")
+
+// worldPadding is the space between maps in the world, in pixels.
+const worldPadding = 4 * pkg.TileWidth
+
+var bar *progressbar.ProgressBar
+
+// mapFile is a map file in batch mode, name is its path relative to -md without extension (or its base name for
+// glob pattern), which is also the sub directory of its output.
+type mapFile struct {
+	path string
+	name string
+}
+
+// convertAll converts the maps found by -md in parallel, and writes the world of converted maps. The files which are
+// not CrossGate maps are skipped, and the failed maps are listed at the end.
+func convertAll(ctx context.Context, res *pkg.Resources, ets *pkg.ExternalTileSet) (err error) {
+	var files []mapFile
+	if files, err = findMaps(f.md); err != nil {
+		return
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("%w: %s", errNoMap, f.md)
+	}
+	jobs := make([]int, len(files))
+	for i := range jobs {
+		jobs[i] = i
+	}
+	sizes := make([]image.Point, len(files)) // pixel size of converted maps, zero for skipped or failed
+	var skipped atomic.Int64
+
+	bar = progressbar.Default(int64(len(files)))
+	errs := worker.Run(ctx, f.jobs, jobs, func(_ context.Context, i int) (err error) {
+		defer func() { _ = bar.Add(1) }()
+
+		var m pkg.Map
+		if m, err = readMap(files[i].path); errors.Is(err, pkg.ErrInvalidMagic) {
+			skipped.Add(1)
+			log.Debug().Msgf("skip %s: %v", files[i].path, err)
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", files[i].path, err)
+		}
+
+		dir := filepath.Join(f.outdir, filepath.FromSlash(files[i].name))
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("%s: %w", files[i].path, err)
+		}
+
+		// each map reads graphics with its own offset
+		gf := io.NewSectionReader(res.GraphicFile, 0, math.MaxInt64)
+
+		if sizes[i], err = convert(m, files[i].name, res, gf, ets, dir); err != nil {
+			return fmt.Errorf("%s: %w", files[i].path, err)
+		}
+
+		return
+	})
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	// the world of Tiled can't load LDtk projects
+	if f.format != "ldtk" {
+		if err = writeWorld(files, sizes); err != nil {
+			return
+		}
+	}
+
+	if len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, e := range errs {
+			msgs[i] = e.Error()
+		}
+		sort.Strings(msgs)
+
+		log.Error().Msgf("%d of %d maps failed:", len(errs), len(files))
+		for _, msg := range msgs {
+			log.Error().Msg(msg)
+		}
+
+		return fmt.Errorf("%w: %d of %d maps", errConvertFailed, len(errs), len(files))
+	}
+
+	log.Info().Msgf("converted %d maps, skipped %d files", len(files)-int(skipped.Load()), skipped.Load())
+
+	return
+}
+
+// findMaps finds the map files of md, a directory is walked recursively, otherwise it's a glob pattern.
+func findMaps(md string) (files []mapFile, err error) {
+	if info, serr := os.Stat(md); serr == nil && info.IsDir() {
+		err = filepath.WalkDir(md, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return err
+			}
+
+			rel, err := filepath.Rel(md, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			files = append(files, mapFile{path: p, name: strings.TrimSuffix(rel, path.Ext(rel))})
+
+			return nil
+		})
+
+		return
+	}
+
+	var matches []string
+	if matches, err = filepath.Glob(md); err != nil {
+		return
+	}
+	for _, p := range matches {
+		if info, serr := os.Stat(p); serr != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		base := filepath.Base(p)
+		files = append(files, mapFile{path: p, name: strings.TrimSuffix(base, filepath.Ext(base))})
+	}
+
+	return
+}
+
+// readMap reads the map file, pkg.ErrInvalidMagic is returned if it doesn't start with "MAP", even if it's shorter
+// than the header.
+func readMap(name string) (m pkg.Map, err error) {
+	var file *os.File
+	if file, err = os.Open(name); err != nil {
+		return
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	if magic, _ := br.Peek(3); string(magic) != "MAP" {
+		return m, fmt.Errorf("%w: %q", pkg.ErrInvalidMagic, magic)
+	}
+
+	return pkg.MakeMap(br)
+}
+
+// writeWorld places the converted maps (non-zero sizes) side by side, and writes them to maps.world in -o.
+func writeWorld(files []mapFile, sizes []image.Point) (err error) {
+	var converted []int
+	for i, s := range sizes {
+		if s != (image.Point{}) {
+			converted = append(converted, i)
+		}
+	}
+
+	packed := make([]image.Point, len(converted))
+	for j, i := range converted {
+		packed[j] = sizes[i]
+	}
+	rects, _ := atlas.Pack(packed, worldPadding)
+
+	world := tmx.NewWorld()
+	for j, i := range converted {
+		world.Maps = append(world.Maps, tmx.WorldMap{
+			FileName: path.Join(files[i].name, f.outmap),
+			X:        rects[j].Min.X,
+			Y:        rects[j].Min.Y,
+			Width:    rects[j].Dx(),
+			Height:   rects[j].Dy(),
+		})
+	}
+
+	var out []byte
+	if out, err = json.MarshalIndent(world, "", "  "); err != nil {
+		return
+	}
+
+	return os.WriteFile(filepath.Join(f.outdir, "maps.world"), out, 0644)
+}
@@ -1,26 +1,46 @@
 package convertmap
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
+	"image"
+	"image/png"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"xgtool/internal/ldtk"
 	"xgtool/internal/tmx"
 	"xgtool/pkg"
+)
 
-	"github.com/rs/zerolog/log"
+var (
+	errInvalidFormat = errors.New("invalid format")
+	errNoMap         = errors.New("no map")
+	errFlagConflict  = errors.New("conflicting flags")
 )
 
 type flags struct {
-	gif    string
-	gf     string
-	pf     string
-	mf     string
-	outdir string
-	outmap string
-	dr     bool // dry-run
+	gif     string
+	gf      string
+	pf      string
+	mf      string
+	md      string // map directory or glob pattern (batch mode)
+	outdir  string
+	outmap  string
+	format  string
+	compact bool
+	tileset string // directory of external tile sets
+	atlas   bool
+	walk    bool
+	jobs    int
+	dr      bool // dry-run
 }
 
 func (f *flags) Flags() *flag.FlagSet {
@@ -29,8 +49,15 @@ func (f *flags) Flags() *flag.FlagSet {
 	fs.StringVar(&f.gf, "gf", "", "graphic file path")
 	fs.StringVar(&f.pf, "pf", "", "palette file path")
 	fs.StringVar(&f.mf, "mf", "", "map file path")
+	fs.StringVar(&f.md, "md", "", "map directory (walked recursively) or glob pattern, converts every map into a sub directory of -o in parallel, and writes maps.world placing them")
 	fs.StringVar(&f.outdir, "o", "output", "output directory")
-	fs.StringVar(&f.outmap, "n", "map.json", "output file name")
+	fs.StringVar(&f.outmap, "n", "", "output file name (default: map.json, map.tmx or map.ldtk by -format)")
+	fs.StringVar(&f.format, "format", "json", "output format: json, tmx (XML) or ldtk (LDtk project)")
+	fs.BoolVar(&f.compact, "compact", false, "write the data of tile layers in base64 with zlib compression, instead of CSV")
+	fs.StringVar(&f.tileset, "tileset-dir", "", "share an external tile set (.tsj or .tsx by -format) named after the graphic info, graphic and palette files in this directory, instead of embedding tile sets")
+	fs.BoolVar(&f.atlas, "atlas", false, "pack the graphics of ground and object tile sets into ground.png and object.png, instead of one image per tile")
+	fs.BoolVar(&f.walk, "walk", false, "also write the walkability grid to walkable.json and walkable.png (mask)")
+	fs.IntVar(&f.jobs, "j", runtime.NumCPU(), "number of parallel workers in batch mode")
 	fs.BoolVar(&f.dr, "dry-run", false, "dump without output files (for testing)")
 
 	return fs
@@ -45,8 +72,26 @@ func ConvertMap(ctx context.Context, args []string) (err error) {
 	if err = f.Flags().Parse(args); err != nil {
 		return
 	}
+	if f.format != "json" && f.format != "tmx" && f.format != "ldtk" {
+		return fmt.Errorf("%w: %s", errInvalidFormat, f.format)
+	}
+	if (f.mf == "") == (f.md == "") {
+		return fmt.Errorf("%w: either -mf or -md is required", errNoMap)
+	}
+	if f.atlas && f.tileset != "" {
+		return fmt.Errorf("%w: -atlas can't be used with -tileset-dir", errFlagConflict)
+	}
+	if f.format == "ldtk" && (f.tileset != "" || f.compact) {
+		return fmt.Errorf("%w: -format ldtk can't be used with -tileset-dir or -compact", errFlagConflict)
+	}
+	if f.outmap == "" {
+		f.outmap = "map." + f.format
+	}
 	if f.dr {
 		f.outdir = os.TempDir()
+		if f.tileset != "" {
+			f.tileset = os.TempDir()
+		}
 	}
 
 	res := pkg.Resources{}
@@ -61,34 +106,140 @@ func ConvertMap(ctx context.Context, args []string) (err error) {
 	if err = res.OpenPalette(f.pf); err != nil {
 		return
 	}
-	if err = res.OpenMap(f.mf); err != nil {
+
+	var ets *pkg.ExternalTileSet
+	if f.tileset != "" {
+		if ets, err = pkg.NewExternalTileSet(
+			tileSetPath(f.tileset, f.gif, f.gf, f.pf),
+			res.GraphicResource.MDx,
+			res.GraphicFile,
+			res.Palette,
+		); err != nil {
+			return
+		}
+	}
+
+	if err = os.MkdirAll(f.outdir, 0755); err != nil {
+		return
+	}
+	// the maps with embedded tile sets share the collision tile in -o, the external tile set writes it on saving
+	if f.format != "ldtk" && ets == nil {
+		if err = pkg.WriteCollisionTile(filepath.Join(f.outdir, pkg.CollisionImage)); err != nil {
+			return
+		}
+	}
+
+	if f.md != "" {
+		err = convertAll(ctx, &res, ets)
+	} else if err = res.OpenMap(f.mf); err == nil {
+		name := strings.TrimSuffix(filepath.Base(f.mf), filepath.Ext(f.mf))
+		_, err = convert(res.Map, name, &res, res.GraphicFile, ets, f.outdir)
+	}
+
+	// the tile set keeps the tiles rendered for the converted maps, even if some maps failed
+	if ets != nil {
+		err = errors.Join(err, ets.Save())
+	}
+
+	return
+}
+
+// convert converts m named name, writes it to dir, and returns its size in pixels. The tile sets are embedded if ets
+// is nil, and their images are read from gf, which can't be shared by goroutines.
+func convert(m pkg.Map, name string, res *pkg.Resources, gf io.ReadSeeker, ets *pkg.ExternalTileSet, dir string) (size image.Point, err error) {
+	var collision string
+	if collision, err = filepath.Rel(dir, filepath.Join(f.outdir, pkg.CollisionImage)); err != nil {
 		return
 	}
 
 	var tm tmx.Map
-	if tm, err = res.Map.TiledMap(
-		res.GraphicResource.MDx,
-		res.GraphicFile,
-		res.Palette,
-		f.outdir,
-	); err != nil {
+	switch {
+	case ets != nil:
+		tm, err = m.TiledMapExternal(ets, dir)
+	case f.atlas || f.format == "ldtk":
+		tm, err = m.TiledMapAtlas(res.GraphicResource.MDx, res.GraphicFile, res.Palette, dir, collision)
+	default:
+		tm, err = m.TiledMap(res.GraphicResource.MDx, gf, res.Palette, dir, collision)
+	}
+	if err != nil {
 		return
 	}
 
 	var out []byte
-	if out, err = json.Marshal(tm); err != nil {
-		log.Err(err).Send()
-		return
+	if f.format == "ldtk" {
+		// the project reuses the atlas images of tm
+		var proj ldtk.Project
+		if proj, err = m.LDtkProject(tm, res.GraphicResource.MDx, name); err != nil {
+			return
+		}
+		if out, err = json.MarshalIndent(proj, "", "  "); err != nil {
+			return
+		}
+		size = image.Pt(proj.Levels[0].PxWid, proj.Levels[0].PxHei)
+	} else {
+		if f.compact {
+			tm.SetEncoding(tmx.Base64, tmx.Zlib)
+		}
+		if out, err = marshal(tm); err != nil {
+			return
+		}
+		size = tm.PixelSize()
 	}
 
-	if err = os.WriteFile(
-		fmt.Sprintf("%s/%s", filepath.Clean(f.outdir), f.outmap),
-		out,
-		0644,
-	); err != nil {
-		log.Err(err).Send()
+	if err = os.WriteFile(filepath.Join(dir, f.outmap), out, 0644); err != nil {
 		return
 	}
 
+	if f.walk {
+		err = writeWalkability(m.Walkability(res.GraphicResource.MDx), dir)
+	}
+
 	return
 }
+
+// tileSetPath returns the path of external tile set in dir, which is keyed on the graphic info, graphic and palette
+// files, because the images rendered from other graphic or palette files can't be reused. Such as
+// "dir/GraphicInfo_66-Graphic_66-palet_00.tsj" for "GraphicInfo_66.bin", "Graphic_66.bin" and "palet_00.cgp".
+func tileSetPath(dir string, files ...string) string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	ext := ".tsj"
+	if f.format == "tmx" {
+		ext = ".tsx"
+	}
+
+	return filepath.Join(dir, strings.Join(names, "-")+ext)
+}
+
+// marshal encodes tm by -format, tmx is the XML format.
+func marshal(tm tmx.Map) (out []byte, err error) {
+	if f.format == "json" {
+		return json.Marshal(tm)
+	}
+
+	if out, err = xml.MarshalIndent(tm, "", " "); err != nil {
+		return
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
+
+// writeWalkability writes the grid as JSON and PNG mask to dir, the cells are in the order of map file, not rotated.
+func writeWalkability(wk pkg.Walkability, dir string) (err error) {
+	var out []byte
+	if out, err = json.Marshal(wk); err != nil {
+		return
+	}
+	if err = os.WriteFile(filepath.Join(dir, "walkable.json"), out, 0644); err != nil {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if err = png.Encode(buf, wk.Mask()); err != nil {
+		return
+	}
+
+	return os.WriteFile(filepath.Join(dir, "walkable.png"), buf.Bytes(), 0644)
+}
@@ -0,0 +1,137 @@
+package detect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"xgtool/pkg"
+)
+
+var errDirRequired = errors.New("directory is required")
+
+type flags struct {
+	json bool
+}
+
+func (f *flags) Flags() (fs *flag.FlagSet) {
+	fs = flag.NewFlagSet("detect", flag.ExitOnError)
+	fs.BoolVar(&f.json, "json", false, "print the result in JSON")
+
+	return
+}
+
+var (
+	f flags
+)
+
+// Detect the entrypoint of "detect" command, the directory of client resources is given as argument.
+func Detect(ctx context.Context, args []string) (err error) {
+	fs := f.Flags()
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: xgtool detect [-json] <dir>", errDirRequired)
+	}
+	dir := fs.Arg(0)
+
+	var d pkg.Detection
+	if d, err = pkg.Detect(os.DirFS(dir)); err != nil {
+		return
+	}
+
+	// the paths are relative to dir, make them usable from current directory
+	for i := range d.Files {
+		d.Files[i].Path = join(dir, d.Files[i].Path)
+	}
+	for i, c := range d.Combos {
+		d.Combos[i] = pkg.Combo{
+			Family:             c.Family,
+			AnimeInfo:          join(dir, c.AnimeInfo),
+			Anime:              join(dir, c.Anime),
+			HeaderSize:         c.HeaderSize,
+			GraphicInfo:        join(dir, c.GraphicInfo),
+			Graphic:            join(dir, c.Graphic),
+			PaletteGraphicInfo: join(dir, c.PaletteGraphicInfo),
+			PaletteGraphic:     join(dir, c.PaletteGraphic),
+			Palette:            join(dir, c.Palette),
+		}
+	}
+
+	if f.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(d)
+	}
+
+	return printDetection(os.Stdout, d)
+}
+
+func join(dir, p string) string {
+	if p == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(p))
+}
+
+func printDetection(w io.Writer, d pkg.Detection) (err error) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "FILE\tKIND\tFAMILY\tVERSION\tRECORDS\tHEADER\tPROBLEM")
+	for _, file := range d.Files {
+		records, header := "-", "-"
+		if file.Kind == pkg.KindGraphicInfo || file.Kind == pkg.KindAnimeInfo {
+			records = fmt.Sprint(file.Records)
+		}
+		if file.Kind == pkg.KindAnime {
+			header = fmt.Sprint(file.HeaderSize)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", file.Path, file.Kind, file.Family, file.Version, records, header, file.Problem)
+	}
+	if err = tw.Flush(); err != nil {
+		return
+	}
+
+	fmt.Fprintln(w)
+	for _, c := range d.Combos {
+		fmt.Fprintf(w, "# %s\n", c.Family)
+		if c.GraphicInfo == "" {
+			fmt.Fprintf(w, "# graphic info and graphic of %s are not found\n", c.Family)
+		}
+		fmt.Fprintln(w, strings.Join(command(c), " \\\n    "))
+		fmt.Fprintln(w)
+	}
+
+	return
+}
+
+// command returns the suggested command line of combo, dump-anime for anime combos, dump-graphic for the others.
+func command(c pkg.Combo) (args []string) {
+	if c.Anime == "" {
+		args = append(args, "xgtool dump-graphic")
+	} else {
+		args = append(args, "xgtool dump-anime", "-aif "+c.AnimeInfo, "-af "+c.Anime)
+	}
+	if c.GraphicInfo == "" {
+		args = append(args, "-gif <GraphicInfo>", "-gf <Graphic>")
+	} else {
+		args = append(args, "-gif "+c.GraphicInfo, "-gf "+c.Graphic)
+	}
+	if c.Palette != "" {
+		args = append(args, "-pf "+c.Palette)
+	}
+	if c.PaletteGraphicInfo != "" {
+		args = append(args, "-pgif "+c.PaletteGraphicInfo, "-pgf "+c.PaletteGraphic)
+	}
+
+	return
+}
@@ -0,0 +1,65 @@
+package dumpanime
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"image/gif"
+	"io"
+	"sort"
+	"strings"
+	"xgtool/internal/apng"
+	"xgtool/pkg"
+)
+
+var errUnknownFormat = errors.New("unknown format")
+
+// encoder renders an anime and encodes it into a specific format.
+type encoder struct {
+	ext    string // file extension without dot
+	encode func(w io.Writer, a pkg.Anime, gf io.ReaderAt, p color.Palette) error
+	sheet  bool // pack all animes of an AnimeIndex into a sprite sheet (.png with .json), encode is not used
+}
+
+var encoders = map[string]encoder{
+	"gif":         {ext: "gif", encode: encodeGIF},
+	"apng":        {ext: "png", encode: encodeAPNG},
+	"spritesheet": {ext: "png", sheet: true},
+}
+
+func formats() string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+func getEncoder(format string) (e encoder, err error) {
+	var ok bool
+	if e, ok = encoders[format]; !ok {
+		return e, fmt.Errorf("%w: %s, available: %s", errUnknownFormat, format, formats())
+	}
+
+	return
+}
+
+func encodeGIF(w io.Writer, a pkg.Anime, gf io.ReaderAt, p color.Palette) (err error) {
+	var img *gif.GIF
+	if img, err = a.GIF(gf, p); err != nil {
+		return
+	}
+
+	return gif.EncodeAll(w, img)
+}
+
+func encodeAPNG(w io.Writer, a pkg.Anime, gf io.ReaderAt, p color.Palette) (err error) {
+	var img *apng.APNG
+	if img, err = a.APNG(gf, p); err != nil {
+		return
+	}
+
+	return apng.Encode(w, img)
+}
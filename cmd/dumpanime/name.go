@@ -0,0 +1,97 @@
+package dumpanime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"xgtool/pkg"
+)
+
+var errInvalidName = errors.New("invalid name")
+
+// defaultName is the default naming template, which puts every direction of an action in a directory.
+const defaultName = "{{.ID}}/{{.ActionName}}/{{.Direction}}"
+
+// nameFields are the fields available in naming template.
+type nameFields struct {
+	ID         pkg.AnimeID
+	Action     pkg.ActionID
+	ActionName string
+	Direction  int16
+}
+
+// namer names the output files of animes, the extension is appended to the executed template.
+type namer struct {
+	tmpl    *template.Template
+	actions pkg.ActionNames
+}
+
+func newNamer(name string, actions pkg.ActionNames) (n namer, err error) {
+	if n.tmpl, err = template.New("name").Option("missingkey=error").Parse(name); err != nil {
+		return n, fmt.Errorf("%w: %v", errInvalidName, err)
+	}
+	n.actions = actions
+
+	// check the template with fake fields, so a bad template fails before dumping
+	_, err = n.name(pkg.Anime{Index: pkg.AnimeIndex{Info: pkg.AnimeInfo{ID: 1}}}, "gif")
+
+	return
+}
+
+// name returns the file name of a relative to output directory.
+func (n namer) name(a pkg.Anime, ext string) (name string, err error) {
+	var sb strings.Builder
+	if err = n.tmpl.Execute(&sb, nameFields{
+		ID:         a.Index.Info.ID,
+		Action:     a.Header.Action,
+		ActionName: n.actions.Name(a.Header.Action),
+		Direction:  a.Header.Direct,
+	}); err != nil {
+		return "", fmt.Errorf("%w: %v", errInvalidName, err)
+	}
+
+	name = filepath.Clean(sb.String())
+	if name == "." || filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q is not in output directory", errInvalidName, sb.String())
+	}
+
+	return name + "." + ext, nil
+}
+
+// indexName returns the file name of the output for all animes of id, such as the sprite sheet and metadata, relative
+// to output directory. They are always in the directory of anime ID, which is the default layout of animes.
+func (n namer) indexName(id pkg.AnimeID, file string) string {
+	return filepath.Join(fmt.Sprint(id), file)
+}
+
+// loadActionNames loads the ActionID => name table from a JSON object, such as {"0": "stand", "1": "walk"}.
+//
+// The names in file are merged into DefaultActionNames.
+func loadActionNames(path string) (actions pkg.ActionNames, err error) {
+	actions = make(pkg.ActionNames, len(pkg.DefaultActionNames))
+	for act, name := range pkg.DefaultActionNames {
+		actions[act] = name
+	}
+	if path == "" {
+		return
+	}
+
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return
+	}
+
+	var names pkg.ActionNames
+	if err = json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for act, name := range names {
+		actions[act] = name
+	}
+
+	return
+}
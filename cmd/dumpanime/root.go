@@ -2,31 +2,41 @@ package dumpanime
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"image/color"
-	"image/gif"
+	"image/png"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"xgtool/internal/worker"
 	"xgtool/pkg"
 
 	"github.com/rs/zerolog/log"
 	"github.com/schollz/progressbar/v3"
 )
 
-var errPaletteNotFound = errors.New("palette not found")
+var errDumpFailed = errors.New("dump failed")
 
 type flags struct {
-	aif    string
-	af     string
-	gif    string
-	gf     string
-	pgif   string
-	pgf    string
-	pf     string
-	outdir string
-	dr     bool // dry-run
+	aif     string
+	af      string
+	gif     string
+	gf      string
+	pgif    string
+	pgf     string
+	pf      string
+	outdir  string
+	format  string
+	name    string
+	actions string
+	jobs    int
+	meta    bool
+	dr      bool // dry-run
 }
 
 func (f *flags) Flags() (fs *flag.FlagSet) {
@@ -39,6 +49,11 @@ func (f *flags) Flags() (fs *flag.FlagSet) {
 	fs.StringVar(&f.pgf, "pgf", "", "palette graphic file path")
 	fs.StringVar(&f.pf, "pf", "", "palette file path")
 	fs.StringVar(&f.outdir, "o", "output", "output directory")
+	fs.StringVar(&f.format, "format", "gif", "output format: "+formats())
+	fs.StringVar(&f.name, "name", defaultName, "naming template of output files without extension, fields: .ID, .Action, .ActionName, .Direction")
+	fs.StringVar(&f.actions, "actions", "", "JSON file of ActionID => name table, such as {\"0\": \"stand\"}, merged into the default names")
+	fs.IntVar(&f.jobs, "j", runtime.NumCPU(), "number of parallel workers")
+	fs.BoolVar(&f.meta, "meta", false, "also write frame metadata (durations, offsets, raw flags) to <animeID>/meta.json")
 	fs.BoolVar(&f.dr, "dry-run", false, "dump without output files (for testing)")
 
 	return
@@ -55,6 +70,20 @@ func DumpAnime(ctx context.Context, args []string) (err error) {
 		return
 	}
 
+	var enc encoder
+	if enc, err = getEncoder(f.format); err != nil {
+		return
+	}
+
+	var actions pkg.ActionNames
+	if actions, err = loadActionNames(f.actions); err != nil {
+		return
+	}
+	var n namer
+	if n, err = newNamer(f.name, actions); err != nil {
+		return
+	}
+
 	res := pkg.Resources{}
 	pres := pkg.Resources{}
 	defer res.Close()
@@ -72,8 +101,10 @@ func DumpAnime(ctx context.Context, args []string) (err error) {
 	if err = res.OpenGraphic(f.gf); err != nil {
 		return
 	}
-	if err = res.OpenPalette(f.pf); err != nil {
-		return
+	if f.pf != "" {
+		if err = res.OpenPalette(f.pf); err != nil {
+			return
+		}
 	}
 	if f.pgif != "" {
 		if err = pres.OpenGraphicResource(f.pgif); err != nil {
@@ -86,86 +117,133 @@ func DumpAnime(ctx context.Context, args []string) (err error) {
 		}
 	}
 
-	bar = progressbar.Default(int64(len(res.AnimeResource)))
-	done := make(chan struct{})
-
-	go func() {
-		defer close(done)
-		for _, ai := range res.AnimeResource {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				var p color.Palette
-				if p, err = palette(res, pres, ai); err != nil {
-					return
-				}
-				if err = dumpAnime(ai, res.AnimeFile, res.GraphicResource, res.GraphicFile, p); err != nil {
-					log.Err(err).Send()
-				}
-				_ = bar.Add(1)
-			}
+	jobs := make([]pkg.AnimeIndex, 0, len(res.AnimeResource))
+	for _, ai := range res.AnimeResource {
+		jobs = append(jobs, ai)
+	}
+
+	r := resolver(res, pres)
+
+	bar = progressbar.Default(int64(len(jobs)))
+	errs := worker.Run(ctx, f.jobs, jobs, func(_ context.Context, ai pkg.AnimeIndex) (err error) {
+		defer func() { _ = bar.Add(1) }()
+
+		if err = dumpAnime(enc, n, ai, res.AnimeFile, res.GraphicResource, res.GraphicFile, r); err != nil {
+			return fmt.Errorf("anime %d: %w", ai.Info.ID, err)
 		}
-	}()
 
-	<-done
+		return
+	})
+
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			log.Error().Msg(e.Error())
+		}
+		return fmt.Errorf("%w: %d of %d animes", errDumpFailed, len(errs), len(jobs))
+	}
 
 	return
 }
 
-func palette(res pkg.Resources, pres pkg.Resources, ai pkg.AnimeIndex) (p color.Palette, err error) {
-	// use hidden palette
-	if len(pres.GraphicResource.MDx) > 0 {
-		if _, ok := pres.GraphicResource.MDx[int32(ai.Info.ID)]; ok {
-			var pg *pkg.Graphic
-			if err = pres.GraphicResource.MDx[int32(ai.Info.ID)][0].Load(pres.GraphicFile); err != nil {
-				return nil, err
-			}
+// resolver makes the palette resolver, the palette graphic keyed by anime ID is tried first, then the cgp palette, and
+// the palette embedded in frames at last.
+func resolver(res pkg.Resources, pres pkg.Resources) (r pkg.PaletteResolverChain) {
+	if pres.GraphicFile != nil {
+		r = append(r, pkg.AnimePaletteResolver{GraphicResource: pres.GraphicResource, GraphicFile: pres.GraphicFile})
+	}
+	if len(res.Palette) > 0 {
+		r = append(r, pkg.CGPPaletteResolver{Palette: res.Palette})
+	}
 
-			return pg.PaletteData, nil
-		}
+	return append(r, pkg.EmbeddedPaletteResolver{GraphicFile: res.GraphicFile})
+}
 
-		log.Debug().Msgf("hidden palette not found: %+v", ai)
+func dumpAnime(enc encoder, n namer, ai pkg.AnimeIndex, af io.ReaderAt, gr pkg.GraphicResource, gf io.ReaderAt, r pkg.PaletteResolver) (err error) {
+	if ai.Animes, err = ai.LoadAt(af, gr); err != nil {
+		return
 	}
 
-	// use cgp palette
-	if len(res.Palette) > 0 {
-		return res.Palette, nil
+	var p color.Palette
+	if p, err = r.Resolve(ai); err != nil {
+		return
+	}
+	if f.meta {
+		if err = dumpMeta(n, ai); err != nil {
+			return
+		}
+	}
+	if enc.sheet {
+		return dumpSpriteSheet(n, ai, gf, p)
 	}
 
-	return nil, fmt.Errorf("%w: %d", errPaletteNotFound, ai.Info.ID)
-}
+	used := make(map[string]bool) // animes with the same action and direction are suffixed with "-n"
+	for _, a := range ai.Sorted() {
+		var name string
+		if name, err = n.name(a, enc.ext); err != nil {
+			return
+		}
+		base := strings.TrimSuffix(name, "."+enc.ext)
+		for i := 1; used[name]; i++ {
+			name = fmt.Sprintf("%s-%d.%s", base, i, enc.ext)
+		}
+		used[name] = true
 
-func dumpAnime(ai pkg.AnimeIndex, af *os.File, gr pkg.GraphicResource, gf *os.File, p color.Palette) (err error) {
-	if err = ai.Load(af, gr); err != nil {
-		return
-	}
-
-	for i, animes := range ai.Animes {
-		for _, a := range animes {
-			var img *gif.GIF
-			if img, err = a.GIF(gf, p); err != nil {
-				log.Err(err).Msgf("anime: %+v", ai.Info)
-				return
-			}
-
-			var out *os.File
-			if f.dr {
-				out, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0644)
-			} else {
-				out, err = os.OpenFile(fmt.Sprintf("%s/%d-%d.gif", filepath.Clean(f.outdir), ai.Info.ID, i), os.O_WRONLY|os.O_CREATE, 0644)
-			}
-			if err != nil {
-				log.Err(err).Msgf("anime: %+v", ai.Info)
-				return
-			}
-
-			if err = gif.EncodeAll(out, img); err != nil {
-				log.Err(err).Msgf("anime: %+v", ai.Info)
-				return
-			}
+		if err = writeFile(filepath.Join(f.outdir, name), func(w io.Writer) error { return enc.encode(w, a, gf, p) }); err != nil {
+			return
 		}
 	}
 
 	return
 }
+
+// writeFile creates the file and its directories, and writes it with encode, existing file is truncated.
+func writeFile(name string, encode func(w io.Writer) error) (err error) {
+	var out *os.File
+	if f.dr {
+		out, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0644)
+	} else {
+		if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return
+		}
+		out, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	}
+	if err != nil {
+		return
+	}
+
+	if err = encode(out); err != nil {
+		_ = out.Close()
+		return
+	}
+
+	return out.Close()
+}
+
+// dumpSpriteSheet writes the sprite sheet of ai and its JSON descriptor, which refers to the image in the same
+// directory.
+func dumpSpriteSheet(n namer, ai pkg.AnimeIndex, gf io.ReaderAt, p color.Palette) (err error) {
+	const sheetImage = "spritesheet.png"
+
+	img, sheet, err := ai.SpriteSheet(gf, p, sheetImage)
+	if err != nil {
+		return
+	}
+
+	if err = writeFile(filepath.Join(f.outdir, n.indexName(ai.Info.ID, sheetImage)), func(w io.Writer) error { return png.Encode(w, img) }); err != nil {
+		return
+	}
+
+	return writeFile(filepath.Join(f.outdir, n.indexName(ai.Info.ID, "spritesheet.json")), func(w io.Writer) error { return json.NewEncoder(w).Encode(sheet) })
+}
+
+func dumpMeta(n namer, ai pkg.AnimeIndex) (err error) {
+	return writeFile(filepath.Join(f.outdir, n.indexName(ai.Info.ID, "meta.json")), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(ai.Meta())
+	})
+}
@@ -0,0 +1,147 @@
+package dumpgraphic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sort"
+	"strings"
+	"xgtool/internal/bmp"
+	"xgtool/pkg"
+)
+
+var errUnknownFormat = errors.New("unknown format")
+
+// encoder encodes the rendered graphic into a specific format.
+type encoder struct {
+	ext      string // file extension without dot
+	paletted bool   // render with Graphic.ImgPaletted instead of Graphic.ImgRGBA
+	palette  bool   // write the palette into a sidecar file (.pal)
+	encode   func(w io.Writer, img image.Image) error
+}
+
+var encoders = map[string]encoder{
+	"png":  {ext: "png", encode: png.Encode},
+	"ipng": {ext: "png", paletted: true, encode: png.Encode},
+	"gif":  {ext: "gif", paletted: true, encode: func(w io.Writer, img image.Image) error { return gif.Encode(w, img, nil) }},
+	"bmp":  {ext: "bmp", encode: bmp.Encode},
+	"jpeg": {ext: "jpg", encode: func(w io.Writer, img image.Image) error { return jpeg.Encode(w, img, &jpeg.Options{Quality: 75}) }},
+	"raw":  {ext: "raw", paletted: true, palette: true, encode: encodeRaw},
+}
+
+func formats() string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+func getEncoder(format string) (e encoder, err error) {
+	var ok bool
+	if e, ok = encoders[format]; !ok {
+		return e, fmt.Errorf("%w: %s, available: %s", errUnknownFormat, format, formats())
+	}
+
+	return
+}
+
+// encodeRaw writes the palette indexes of img, row by row from top to bottom.
+func encodeRaw(w io.Writer, img image.Image) (err error) {
+	p := img.(*image.Paletted)
+	b := p.Bounds()
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		i := p.PixOffset(b.Min.X, y)
+		if _, err = w.Write(p.Pix[i : i+b.Dx()]); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// encodePalette writes the palette of img as RGBA bytes, 4 bytes per color.
+func encodePalette(w io.Writer, img image.Image) (err error) {
+	p := img.(*image.Paletted)
+
+	buf := make([]byte, 0, len(p.Palette)*4)
+	for _, c := range p.Palette {
+		r, g, b, a := c.RGBA()
+		buf = append(buf, byte(r>>8), byte(g>>8), byte(b>>8), byte(a>>8))
+	}
+
+	_, err = w.Write(buf)
+
+	return
+}
+
+// render renders the graphic into the image type required by the encoder.
+//
+// When anchored, the graphic is drawn on a canvas containing its anchor (see pkg.GraphicInfo.Anchor).
+func (e encoder) render(g *pkg.Graphic, p color.Palette, anchored bool) (img image.Image, anchor image.Point, err error) {
+	switch {
+	case e.paletted && anchored:
+		var pi *image.Paletted
+		if pi, anchor, err = g.ImgPalettedAnchored(p); err != nil {
+			return
+		}
+		return pi, anchor, nil
+	case e.paletted:
+		var pi *image.Paletted
+		if pi, err = g.ImgPaletted(p); err != nil {
+			return
+		}
+		return pi, image.Pt(-int(g.Info.OffX), -int(g.Info.OffY)), nil
+	case anchored:
+		var ri *image.RGBA
+		if ri, anchor, err = g.ImgRGBAAnchored(p); err != nil {
+			return
+		}
+		return ri, anchor, nil
+	default:
+		var ri *image.RGBA
+		if ri, err = g.ImgRGBA(p); err != nil {
+			return
+		}
+		return ri, image.Pt(-int(g.Info.OffX), -int(g.Info.OffY)), nil
+	}
+}
+
+// anchorMeta is the JSON sidecar of an anchored graphic.
+type anchorMeta struct {
+	ID      int32 `json:"id"`
+	MapID   int32 `json:"mapId"`
+	Width   int   `json:"width"`   // Width of image
+	Height  int   `json:"height"`  // Height of image
+	AnchorX int   `json:"anchorX"` // X of the anchor in image
+	AnchorY int   `json:"anchorY"` // Y of the anchor in image
+	OffX    int32 `json:"offX"`    // GraphicInfo.OffX
+	OffY    int32 `json:"offY"`    // GraphicInfo.OffY
+}
+
+func encodeAnchor(info pkg.GraphicInfo, anchor image.Point) func(w io.Writer, img image.Image) error {
+	return func(w io.Writer, img image.Image) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(anchorMeta{
+			ID:      info.ID,
+			MapID:   info.MapID,
+			Width:   img.Bounds().Dx(),
+			Height:  img.Bounds().Dy(),
+			AnchorX: anchor.X,
+			AnchorY: anchor.Y,
+			OffX:    info.OffX,
+			OffY:    info.OffY,
+		})
+	}
+}
@@ -7,20 +7,27 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/jpeg"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"xgtool/internal/worker"
 	"xgtool/pkg"
 
 	"github.com/rs/zerolog/log"
 	"github.com/schollz/progressbar/v3"
 )
 
+var errDumpFailed = errors.New("dump failed")
+
 type flags struct {
 	gif    string
 	gf     string
 	pf     string
 	outdir string
+	format string
+	anchor bool
+	jobs   int
 	dr     bool // dry-run
 }
 
@@ -30,6 +37,9 @@ func (f *flags) Flags() (fs *flag.FlagSet) {
 	fs.StringVar(&f.gf, "gf", "", "graphic file path")
 	fs.StringVar(&f.pf, "pf", "", "palette file path")
 	fs.StringVar(&f.outdir, "o", "output", "output directory")
+	fs.StringVar(&f.format, "format", "jpeg", "output format: "+formats()+" (png keeps the transparency, raw writes palette indexes with a .pal sidecar of RGBA colors)")
+	fs.BoolVar(&f.anchor, "anchor", false, "draw graphic on a canvas containing its anchor (OffX, OffY), and write anchor into a .json sidecar")
+	fs.IntVar(&f.jobs, "j", runtime.NumCPU(), "number of parallel workers")
 	fs.BoolVar(&f.dr, "dry-run", false, "dump without output files (for testing)")
 
 	return
@@ -46,6 +56,11 @@ func DumpGraphic(ctx context.Context, args []string) (err error) {
 		return
 	}
 
+	var enc encoder
+	if enc, err = getEncoder(f.format); err != nil {
+		return
+	}
+
 	res := pkg.Resources{}
 	defer res.Close()
 	if err = res.OpenGraphicResource(f.gif); err != nil {
@@ -58,36 +73,43 @@ func DumpGraphic(ctx context.Context, args []string) (err error) {
 		return
 	}
 
-	bar = progressbar.Default(int64(len(res.GraphicResource.IDx)))
-	done := make(chan struct{})
+	jobs := make([][]*pkg.Graphic, 0, len(res.GraphicResource.IDx))
+	for _, gifs := range res.GraphicResource.IDx {
+		jobs = append(jobs, gifs)
+	}
+
+	bar = progressbar.Default(int64(len(jobs)))
+	errs := worker.Run(ctx, f.jobs, jobs, func(ctx context.Context, gifs []*pkg.Graphic) (err error) {
+		defer func() { _ = bar.Add(1) }()
 
-	go func() {
-		defer close(done)
-		for _, gifs := range res.GraphicResource.IDx {
-			select {
-			case <-ctx.Done():
+		for i, gif := range gifs {
+			if err = ctx.Err(); err != nil {
 				return
-			default:
-				for i, gif := range gifs {
-					if err = dumpGraphic(gif.Info, res.GraphicFile, res.Palette, i); err != nil {
-						log.Err(err).Send()
-						return
-					}
-				}
-
-				_ = bar.Add(1)
 			}
+			if err = dumpGraphic(enc, gif.Info, res.GraphicFile, res.Palette, i); err != nil {
+				return fmt.Errorf("graphic %d (%d): %w", gif.Info.ID, i, err)
+			}
+		}
+
+		return
+	})
 
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			log.Error().Msg(e.Error())
 		}
-	}()
-	<-done
+		return fmt.Errorf("%w: %d of %d graphics", errDumpFailed, len(errs), len(jobs))
+	}
 
 	return nil
 }
 
-func dumpGraphic(info pkg.GraphicInfo, gf *os.File, palette color.Palette, serial int) (err error) {
+func dumpGraphic(enc encoder, info pkg.GraphicInfo, gf io.ReaderAt, palette color.Palette, serial int) (err error) {
 	var g *pkg.Graphic
-	g, err = info.LoadGraphic(gf)
+	g, err = info.LoadGraphicAt(gf)
 	if err != nil && (errors.Is(err, pkg.ErrInvalidMagic) || errors.Is(err, pkg.ErrDecodeFailed)) {
 		log.Warn().Msgf("Invalid Graphic: %+v", err)
 		return nil
@@ -96,29 +118,41 @@ func dumpGraphic(info pkg.GraphicInfo, gf *os.File, palette color.Palette, seria
 	}
 
 	var img image.Image
-	if img, err = g.ImgRGBA(palette); err != nil && (errors.Is(err, pkg.ErrRenderFailed) || errors.Is(err, pkg.ErrEmptyPalette)) {
+	var anchor image.Point
+	if img, anchor, err = enc.render(g, palette, f.anchor); err != nil && (errors.Is(err, pkg.ErrRenderFailed) || errors.Is(err, pkg.ErrEmptyPalette)) {
 		log.Warn().Msgf("Failed to render: %+v", err)
 		return nil
 	} else if err != nil {
 		return err
 	}
 
+	name := fmt.Sprintf("%s/%d-%d", filepath.Clean(f.outdir), g.Info.ID, serial)
+	if err = writeFile(name+"."+enc.ext, img, enc.encode); err != nil {
+		return
+	}
+	if enc.palette {
+		if err = writeFile(name+".pal", img, encodePalette); err != nil {
+			return
+		}
+	}
+	if f.anchor {
+		err = writeFile(name+".json", img, encodeAnchor(g.Info, anchor))
+	}
+
+	return
+}
+
+func writeFile(name string, img image.Image, encode func(io.Writer, image.Image) error) (err error) {
 	var out *os.File
 	if f.dr {
 		out, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0644)
 	} else {
-		out, err = os.OpenFile(fmt.Sprintf("%s/%d-%d.jpg", filepath.Clean(f.outdir), g.Info.ID, serial), os.O_WRONLY|os.O_CREATE, 0644)
+		out, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	}
 	if err != nil {
-		log.Err(err).Send()
 		return
 	}
 	defer out.Close()
 
-	if err = jpeg.Encode(out, img, &jpeg.Options{Quality: 75}); err != nil {
-		log.Err(err).Send()
-		return
-	}
-
-	return err
+	return encode(out, img)
 }
@@ -0,0 +1,207 @@
+package importgraphic
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF decoder
+	_ "image/png" // register PNG decoder
+	"math"
+	"os"
+	"xgtool/pkg"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	errPaletteRequired = errors.New("palette file is required for version 0 and 1")
+	errOutOfRange      = errors.New("out of range")
+)
+
+type flags struct {
+	gif     string
+	gf      string
+	pf      string
+	version uint
+	id      int
+	mapID   int
+	offX    int
+	offY    int
+	gridW   uint
+	gridH   uint
+	access  uint
+	dr      bool // dry-run
+}
+
+func (f *flags) Flags() (fs *flag.FlagSet) {
+	fs = flag.NewFlagSet("import-graphic", flag.ExitOnError)
+	fs.StringVar(&f.gif, "gif", "", "graphic info file path (append to)")
+	fs.StringVar(&f.gf, "gf", "", "graphic file path (append to)")
+	fs.StringVar(&f.pf, "pf", "", "palette file path, required for version 0 and 1")
+	fs.UintVar(&f.version, "v", 1, "graphic header version: 0 (raw), 1 (encoded), 2 (raw with palette), 3 (encoded with palette)")
+	fs.IntVar(&f.id, "id", 0, "graphic id of the first image, increased by 1 for each image")
+	fs.IntVar(&f.mapID, "mid", 0, "map id of the first image, increased by 1 for each image (0 for not used in map)")
+	fs.IntVar(&f.offX, "offx", 0, "x offset of graphic")
+	fs.IntVar(&f.offY, "offy", 0, "y offset of graphic")
+	fs.UintVar(&f.gridW, "gw", 1, "grid width of graphic")
+	fs.UintVar(&f.gridH, "gh", 1, "grid height of graphic")
+	fs.UintVar(&f.access, "access", 0, "access flag of graphic")
+	fs.BoolVar(&f.dr, "dry-run", false, "import without output files (for testing)")
+
+	return
+}
+
+var (
+	f flags
+)
+
+// ImportGraphic the entrypoint of "import-graphic" command, the images are given as arguments.
+func ImportGraphic(ctx context.Context, args []string) (err error) {
+	fs := f.Flags()
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if err = f.validate(fs.NArg()); err != nil {
+		return
+	}
+
+	res := pkg.Resources{}
+	defer res.Close()
+
+	if f.pf != "" {
+		if err = res.OpenPalette(f.pf); err != nil {
+			return
+		}
+	} else if f.version < 2 {
+		return errPaletteRequired
+	}
+
+	var gif, gf *os.File
+	var addr int32
+	if gif, gf, addr, err = openOutput(); err != nil {
+		return
+	}
+	defer gif.Close()
+	defer gf.Close()
+
+	w := pkg.NewGraphicWriterAt(gif, gf, addr)
+	for i, name := range fs.Args() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		var info pkg.GraphicInfo
+		if info, err = importGraphic(w, name, res.Palette, i); err != nil {
+			log.Err(err).Msgf("image: %s", name)
+			return
+		}
+
+		log.Info().Msgf("%s: id=%d, mapID=%d, addr=%d, len=%d", name, info.ID, info.MapID, info.Addr, info.Len)
+	}
+
+	return
+}
+
+// validate checks the flags fit in the fields of graphic info and header, the IDs are checked for n images, so they
+// don't wrap around.
+func (f *flags) validate(n int) error {
+	last := max(n-1, 0)
+
+	switch {
+	case f.version > 3:
+		return fmt.Errorf("%w: -v %d, must be 0 to 3", errOutOfRange, f.version)
+	case f.gridW > math.MaxUint8:
+		return fmt.Errorf("%w: -gw %d, must be 0 to %d", errOutOfRange, f.gridW, math.MaxUint8)
+	case f.gridH > math.MaxUint8:
+		return fmt.Errorf("%w: -gh %d, must be 0 to %d", errOutOfRange, f.gridH, math.MaxUint8)
+	case f.access > math.MaxUint8:
+		return fmt.Errorf("%w: -access %d, must be 0 to %d", errOutOfRange, f.access, math.MaxUint8)
+	case f.id < 0 || f.id > math.MaxInt32-last:
+		return fmt.Errorf("%w: -id %d for %d images, must be 0 to %d", errOutOfRange, f.id, n, math.MaxInt32-last)
+	case f.mapID < 0 || f.mapID > math.MaxInt32-last:
+		return fmt.Errorf("%w: -mid %d for %d images, must be 0 to %d", errOutOfRange, f.mapID, n, math.MaxInt32-last)
+	case f.offX < math.MinInt32 || f.offX > math.MaxInt32:
+		return fmt.Errorf("%w: -offx %d", errOutOfRange, f.offX)
+	case f.offY < math.MinInt32 || f.offY > math.MaxInt32:
+		return fmt.Errorf("%w: -offy %d", errOutOfRange, f.offY)
+	}
+
+	return nil
+}
+
+func openOutput() (gif, gf *os.File, addr int32, err error) {
+	if f.dr {
+		if gif, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0644); err != nil {
+			return
+		}
+		gf, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0644)
+		return
+	}
+
+	if gif, err = os.OpenFile(f.gif, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+		return
+	}
+	if gf, err = os.OpenFile(f.gf, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+		_ = gif.Close()
+		return
+	}
+
+	var fi os.FileInfo
+	if fi, err = gf.Stat(); err != nil {
+		_ = gif.Close()
+		_ = gf.Close()
+		return
+	}
+	if fi.Size() > math.MaxInt32 {
+		_ = gif.Close()
+		_ = gf.Close()
+		return nil, nil, 0, fmt.Errorf("%w: size of %s is %d, the address must fit in int32", errOutOfRange, f.gf, fi.Size())
+	}
+
+	return gif, gf, int32(fi.Size()), nil
+}
+
+func importGraphic(w *pkg.GraphicWriter, name string, p color.Palette, serial int) (info pkg.GraphicInfo, err error) {
+	var in *os.File
+	if in, err = os.Open(name); err != nil {
+		return
+	}
+	defer in.Close()
+
+	var img image.Image
+	if img, _, err = image.Decode(in); err != nil {
+		return
+	}
+
+	if f.version >= 2 && len(p) == 0 {
+		if p, err = pkg.NewPaletteFromImage(img); err != nil {
+			return
+		}
+	}
+
+	var g *pkg.Graphic
+	if g, err = pkg.NewGraphicFromImage(img, p, byte(f.version)); err != nil {
+		return
+	}
+
+	g.Info.ID = int32(f.id + serial)
+	if f.mapID != 0 {
+		g.Info.MapID = int32(f.mapID + serial)
+	}
+	g.Info.OffX = int32(f.offX)
+	g.Info.OffY = int32(f.offY)
+	g.Info.GridW = byte(f.gridW)
+	g.Info.GridH = byte(f.gridH)
+	g.Info.Access = byte(f.access)
+
+	if info, err = w.Write(g); err != nil {
+		return
+	}
+
+	return info, nil
+}
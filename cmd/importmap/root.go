@@ -0,0 +1,87 @@
+package importmap
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"xgtool/internal/tmx"
+	"xgtool/pkg"
+)
+
+type flags struct {
+	gif string
+	tm  string
+	out string
+	dr  bool // dry-run
+}
+
+func (f *flags) Flags() (fs *flag.FlagSet) {
+	fs = flag.NewFlagSet("import-map", flag.ExitOnError)
+	fs.StringVar(&f.gif, "gif", "", "graphic info file path")
+	fs.StringVar(&f.tm, "tm", "", "tiled map file path, JSON or XML (.tmx) converted by convert-map")
+	fs.StringVar(&f.out, "o", "map.dat", "output map file path")
+	fs.BoolVar(&f.dr, "dry-run", false, "import without output file (for testing)")
+
+	return
+}
+
+var (
+	f flags
+)
+
+// ImportMap the entrypoint of "import-map" command
+func ImportMap(ctx context.Context, args []string) (err error) {
+	if err = f.Flags().Parse(args); err != nil {
+		return
+	}
+
+	res := pkg.Resources{}
+	defer res.Close()
+
+	if err = res.OpenGraphicResource(f.gif); err != nil {
+		return
+	}
+
+	var tm tmx.Map
+	if tm, err = readTiledMap(f.tm); err != nil {
+		return
+	}
+
+	var m pkg.Map
+	if m, err = pkg.MakeMapFromTiled(tm, res.GraphicResource.MDx); err != nil {
+		return
+	}
+
+	var out *os.File
+	if f.dr {
+		out, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0644)
+	} else {
+		out, err = os.OpenFile(f.out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	}
+	if err != nil {
+		return
+	}
+	defer out.Close()
+
+	return m.Write(out)
+}
+
+// readTiledMap reads the tiled map in XML if the extension is .tmx, or in JSON otherwise.
+func readTiledMap(name string) (tm tmx.Map, err error) {
+	var b []byte
+	if b, err = os.ReadFile(name); err != nil {
+		return
+	}
+
+	if strings.EqualFold(filepath.Ext(name), ".tmx") {
+		err = xml.Unmarshal(b, &tm)
+	} else {
+		err = json.Unmarshal(b, &tm)
+	}
+
+	return
+}
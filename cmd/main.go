@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"github.com/cristalhq/acmd"
 	"xgtool/cmd/convertmap"
+	"xgtool/cmd/detect"
 	"xgtool/cmd/dumpanime"
 	"xgtool/cmd/dumpgraphic"
+	"xgtool/cmd/importgraphic"
+	"xgtool/cmd/importmap"
+	"xgtool/cmd/path"
+	"xgtool/cmd/rendermap"
 )
 
 var appVersion = ""
@@ -23,11 +28,36 @@ func main() {
 			Description: "Dump anime from anime & anime info file",
 			ExecFunc:    dumpanime.DumpAnime,
 		},
+		{
+			Name:        "import-graphic",
+			Description: "Import images into graphic & graphic info file",
+			ExecFunc:    importgraphic.ImportGraphic,
+		},
 		{
 			Name:        "convert-map",
 			Description: "Convert map into TMX format",
 			ExecFunc:    convertmap.ConvertMap,
 		},
+		{
+			Name:        "import-map",
+			Description: "Import map from TMX format, the reverse of convert-map",
+			ExecFunc:    importmap.ImportMap,
+		},
+		{
+			Name:        "render-map",
+			Description: "Render map into an isometric image",
+			ExecFunc:    rendermap.RenderMap,
+		},
+		{
+			Name:        "detect",
+			Description: "Detect resource files in a directory and suggest their combinations",
+			ExecFunc:    detect.Detect,
+		},
+		{
+			Name:        "path",
+			Description: "Find a walkable route between two cells of map",
+			ExecFunc:    path.Path,
+		},
 	}
 
 	r := acmd.RunnerOf(cmds, acmd.Config{
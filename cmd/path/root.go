@@ -0,0 +1,212 @@
+package path
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+	"xgtool/pkg"
+	"xgtool/pkg/pathfind"
+)
+
+var (
+	errInvalidPoint    = errors.New("invalid point")
+	errInvalidDiagonal = errors.New("invalid diagonal")
+)
+
+// diagonals are the values of "-diagonal" flag.
+var diagonals = map[string]pathfind.Diagonal{
+	"no-corner":  pathfind.NoCorner,
+	"one-corner": pathfind.OneCorner,
+	"always":     pathfind.Always,
+	"never":      pathfind.Never,
+}
+
+type flags struct {
+	gif      string
+	gf       string
+	pf       string
+	mf       string
+	from     string
+	to       string
+	diagonal string
+	out      string
+	scale    float64
+	dr       bool // dry-run
+}
+
+func (f *flags) Flags() (fs *flag.FlagSet) {
+	fs = flag.NewFlagSet("path", flag.ExitOnError)
+	fs.StringVar(&f.gif, "gif", "", "graphic info file path")
+	fs.StringVar(&f.gf, "gf", "", "graphic file path, required by -o")
+	fs.StringVar(&f.pf, "pf", "", "palette file path, required by -o")
+	fs.StringVar(&f.mf, "mf", "", "map file path")
+	fs.StringVar(&f.from, "from", "", "start cell in x,y, x is column and y is row")
+	fs.StringVar(&f.to, "to", "", "goal cell in x,y, x is column and y is row")
+	fs.StringVar(&f.diagonal, "diagonal", "no-corner", "rule of diagonal moves: no-corner, one-corner, always or never")
+	fs.StringVar(&f.out, "o", "", "draw the route over the rendered map into this file (default: print the route only)")
+	fs.Float64Var(&f.scale, "scale", 1, "scale of output image, such as 0.5")
+	fs.BoolVar(&f.dr, "dry-run", false, "draw the route of -o without writing the file (for testing)")
+
+	return
+}
+
+var (
+	f flags
+)
+
+// Path the entrypoint of "path" command
+func Path(ctx context.Context, args []string) (err error) {
+	if err = f.Flags().Parse(args); err != nil {
+		return
+	}
+
+	var from, to image.Point
+	if from, err = parsePoint(f.from); err != nil {
+		return
+	}
+	if to, err = parsePoint(f.to); err != nil {
+		return
+	}
+	d, ok := diagonals[f.diagonal]
+	if !ok {
+		return fmt.Errorf("%w: %s", errInvalidDiagonal, f.diagonal)
+	}
+
+	res := pkg.Resources{}
+	defer res.Close()
+
+	if err = res.OpenGraphicResource(f.gif); err != nil {
+		return
+	}
+	if err = res.OpenMap(f.mf); err != nil {
+		return
+	}
+
+	var route []image.Point
+	if route, err = pathfind.Find(res.Map.Walkability(res.GraphicResource.MDx), from, to, d); err != nil {
+		return
+	}
+	for _, p := range route {
+		fmt.Printf("%d,%d\n", p.X, p.Y)
+	}
+
+	// the map is rendered only for -o, so -gf and -pf are not needed otherwise
+	if f.out == "" {
+		return
+	}
+
+	if err = res.OpenGraphic(f.gf); err != nil {
+		return
+	}
+	if err = res.OpenPalette(f.pf); err != nil {
+		return
+	}
+
+	return drawRoute(res, route)
+}
+
+// drawRoute renders the map and draws route over it.
+func drawRoute(res pkg.Resources, route []image.Point) (err error) {
+	r := pkg.MapRenderer{
+		Index:       res.GraphicResource.MDx,
+		GraphicFile: res.GraphicFile,
+		Palette:     res.Palette,
+		Scale:       f.scale,
+	}
+
+	var mi pkg.MapImage
+	if mi, err = r.Render(res.Map); err != nil {
+		return
+	}
+
+	red := color.RGBA{R: 0xff, A: 0xff}
+	for i := 1; i < len(route); i++ {
+		line(mi.RGBA, mi.Project(route[i-1].X, route[i-1].Y), mi.Project(route[i].X, route[i].Y), red)
+	}
+	dot(mi.RGBA, mi.Project(route[0].X, route[0].Y), 4, color.RGBA{G: 0xff, A: 0xff})
+	dot(mi.RGBA, mi.Project(route[len(route)-1].X, route[len(route)-1].Y), 4, color.RGBA{B: 0xff, A: 0xff})
+
+	var out *os.File
+	if f.dr {
+		out, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0644)
+	} else {
+		out, err = os.OpenFile(f.out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	}
+	if err != nil {
+		return
+	}
+	defer out.Close()
+
+	return png.Encode(out, mi)
+}
+
+// line draws a line from a to b with Bresenham's algorithm, 3 pixels wide.
+func line(img *image.RGBA, a, b image.Point, c color.Color) {
+	dx, dy := abs(b.X-a.X), -abs(b.Y-a.Y)
+	sx, sy := sign(b.X-a.X), sign(b.Y-a.Y)
+	e := dx + dy
+
+	for p := a; ; {
+		dot(img, p, 1, c)
+		if p == b {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			p.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			p.Y += sy
+		}
+	}
+}
+
+// dot draws a square of (2r+1)*(2r+1) pixels centered at p.
+func dot(img *image.RGBA, p image.Point, r int, c color.Color) {
+	draw.Draw(img, image.Rect(p.X-r, p.Y-r, p.X+r+1, p.Y+r+1), image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// parsePoint parses x,y.
+func parsePoint(s string) (p image.Point, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return p, fmt.Errorf("%w: %q, x,y is required", errInvalidPoint, s)
+	}
+	if p.X, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+		return p, fmt.Errorf("%w: %q", errInvalidPoint, s)
+	}
+	if p.Y, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+		return p, fmt.Errorf("%w: %q", errInvalidPoint, s)
+	}
+
+	return
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,148 @@
+package rendermap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+	"xgtool/pkg"
+)
+
+var (
+	errInvalidColor = errors.New("invalid color")
+	errInvalidCrop  = errors.New("invalid crop")
+)
+
+type flags struct {
+	gif   string
+	gf    string
+	pf    string
+	mf    string
+	out   string
+	scale float64
+	bg    string
+	crop  string
+	dr    bool // dry-run
+}
+
+func (f *flags) Flags() (fs *flag.FlagSet) {
+	fs = flag.NewFlagSet("render-map", flag.ExitOnError)
+	fs.StringVar(&f.gif, "gif", "", "graphic info file path")
+	fs.StringVar(&f.gf, "gf", "", "graphic file path")
+	fs.StringVar(&f.pf, "pf", "", "palette file path")
+	fs.StringVar(&f.mf, "mf", "", "map file path")
+	fs.StringVar(&f.out, "o", "map.png", "output file path")
+	fs.Float64Var(&f.scale, "scale", 1, "scale of output image, such as 0.5")
+	fs.StringVar(&f.bg, "bg", "", "background color in #RRGGBB or #RRGGBBAA (default: transparent)")
+	fs.StringVar(&f.crop, "crop", "", "render the cells in x0,y0,x1,y1 only, x is column and y is row, x1 and y1 are exclusive")
+	fs.BoolVar(&f.dr, "dry-run", false, "render without output file (for testing)")
+
+	return
+}
+
+var (
+	f flags
+)
+
+// RenderMap the entrypoint of "render-map" command
+func RenderMap(ctx context.Context, args []string) (err error) {
+	if err = f.Flags().Parse(args); err != nil {
+		return
+	}
+
+	r := pkg.MapRenderer{Scale: f.scale}
+	if r.Background, err = parseColor(f.bg); err != nil {
+		return
+	}
+	if r.Crop, err = parseCrop(f.crop); err != nil {
+		return
+	}
+
+	res := pkg.Resources{}
+	defer res.Close()
+
+	if err = res.OpenGraphicResource(f.gif); err != nil {
+		return
+	}
+	if err = res.OpenGraphic(f.gf); err != nil {
+		return
+	}
+	if err = res.OpenPalette(f.pf); err != nil {
+		return
+	}
+	if err = res.OpenMap(f.mf); err != nil {
+		return
+	}
+	r.Index, r.GraphicFile, r.Palette = res.GraphicResource.MDx, res.GraphicFile, res.Palette
+
+	var mi pkg.MapImage
+	if mi, err = r.Render(res.Map); err != nil {
+		return
+	}
+
+	var out *os.File
+	if f.dr {
+		out, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0644)
+	} else {
+		out, err = os.OpenFile(f.out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	}
+	if err != nil {
+		return
+	}
+	defer out.Close()
+
+	return png.Encode(out, mi)
+}
+
+// parseColor parses #RRGGBB or #RRGGBBAA, nil is returned for empty string.
+func parseColor(s string) (c color.Color, err error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	var v uint64
+	if len(hex) != 8 {
+		return nil, fmt.Errorf("%w: %s", errInvalidColor, s)
+	}
+	if v, err = strconv.ParseUint(hex, 16, 32); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidColor, s)
+	}
+
+	// the alpha is not premultiplied in hex string
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// parseCrop parses x0,y0,x1,y1, an empty rectangle is returned for empty string.
+func parseCrop(s string) (r image.Rectangle, err error) {
+	if s == "" {
+		return
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return r, fmt.Errorf("%w: %s", errInvalidCrop, s)
+	}
+
+	var v [4]int
+	for i, p := range parts {
+		if v[i], err = strconv.Atoi(strings.TrimSpace(p)); err != nil {
+			return r, fmt.Errorf("%w: %s", errInvalidCrop, s)
+		}
+	}
+	if v[0] >= v[2] || v[1] >= v[3] {
+		return r, fmt.Errorf("%w: %s, x0 < x1 and y0 < y1 are required", errInvalidCrop, s)
+	}
+
+	return image.Rect(v[0], v[1], v[2], v[3]), nil
+}
@@ -0,0 +1,163 @@
+package apng
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"io"
+)
+
+// ErrInvalidFrame is returned when the frames are empty, or the sizes of frames are different.
+var ErrInvalidFrame = errors.New("invalid frame")
+
+var signature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// Delay is the frame delay in seconds (Num / Den), see fcTL chunk.
+type Delay struct {
+	Num uint16
+	Den uint16
+}
+
+// APNG represents an animated PNG, all frames have the same size and are drawn from (0, 0).
+type APNG struct {
+	Image     []image.Image
+	Delay     []Delay
+	LoopCount int // 0 for infinite
+}
+
+// NewDelay makes the delay of each frame when total milliseconds is split into cnt frames.
+//
+// The fraction is reduced to fit in uint16, otherwise it's rounded to milliseconds.
+func NewDelay(total, cnt int) Delay {
+	if cnt <= 0 || total <= 0 {
+		return Delay{Num: 0, Den: 1000}
+	}
+
+	num, den := total, cnt*1000
+	g := gcd(num, den)
+	num, den = num/g, den/g
+	if num <= 0xffff && den <= 0xffff {
+		return Delay{Num: uint16(num), Den: uint16(den)}
+	}
+
+	return Delay{Num: uint16(min((total+cnt/2)/cnt, 0xffff)), Den: 1000}
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
+
+// Encode writes the APNG to w, frames are stored as 8-bit RGBA.
+func Encode(w io.Writer, a *APNG) (err error) {
+	if len(a.Image) == 0 || len(a.Image) != len(a.Delay) {
+		return fmt.Errorf("%w: len(Image)=%d, len(Delay)=%d", ErrInvalidFrame, len(a.Image), len(a.Delay))
+	}
+
+	b := a.Image[0].Bounds()
+	e := encoder{w: w}
+
+	e.write(signature)
+	e.chunk("IHDR", u32(uint32(b.Dx())), u32(uint32(b.Dy())), []byte{8, 6, 0, 0, 0}) // 8-bit RGBA, no interlace
+	e.chunk("acTL", u32(uint32(len(a.Image))), u32(uint32(a.LoopCount)))
+
+	for i, img := range a.Image {
+		if img.Bounds().Size() != b.Size() {
+			return fmt.Errorf("%w: frame %d: size %v, want %v", ErrInvalidFrame, i, img.Bounds().Size(), b.Size())
+		}
+
+		// fcTL: sequence, width, height, x, y, delay num, delay den, dispose op (none), blend op (source)
+		e.chunk("fcTL", u32(e.seq), u32(uint32(b.Dx())), u32(uint32(b.Dy())), u32(0), u32(0),
+			u16(a.Delay[i].Num), u16(a.Delay[i].Den), []byte{0, 0})
+		e.seq++
+
+		var data []byte
+		if data, err = compress(img); err != nil {
+			return
+		}
+
+		if i == 0 {
+			e.chunk("IDAT", data)
+		} else {
+			e.chunk("fdAT", u32(e.seq), data)
+			e.seq++
+		}
+	}
+
+	e.chunk("IEND")
+
+	return e.err
+}
+
+type encoder struct {
+	w   io.Writer
+	seq uint32 // sequence number of fcTL and fdAT
+	err error
+}
+
+func (e *encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func (e *encoder) chunk(name string, data ...[]byte) {
+	var n int
+	for _, d := range data {
+		n += len(d)
+	}
+
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write([]byte(name))
+
+	e.write(u32(uint32(n)))
+	e.write([]byte(name))
+	for _, d := range data {
+		_, _ = crc.Write(d)
+		e.write(d)
+	}
+	e.write(u32(crc.Sum32()))
+}
+
+// compress makes the zlib stream of image data, each row starts with filter type 0 (none).
+func compress(img image.Image) ([]byte, error) {
+	b := img.Bounds()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+
+	row := make([]byte, 1+b.Dx()*4)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			i := 1 + (x-b.Min.X)*4
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func u32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func u16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
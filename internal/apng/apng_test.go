@@ -0,0 +1,122 @@
+package apng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewDelay(t *testing.T) {
+	testcases := []struct {
+		name   string
+		total  int
+		cnt    int
+		expect Delay
+	}{
+		{name: "exact", total: 1000, cnt: 10, expect: Delay{Num: 1, Den: 10}},
+		{name: "fraction", total: 1000, cnt: 3, expect: Delay{Num: 1, Den: 3}},
+		{name: "milliseconds", total: 125, cnt: 2, expect: Delay{Num: 1, Den: 16}},
+		{name: "overflow", total: 100003, cnt: 7, expect: Delay{Num: 14286, Den: 1000}},
+		{name: "no frames", total: 1000, cnt: 0, expect: Delay{Num: 0, Den: 1000}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if d := NewDelay(tc.total, tc.cnt); d != tc.expect {
+				t.Errorf("expected delay: %+v, got %+v", tc.expect, d)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	frames := make([]image.Image, 3)
+	for i := range frames {
+		img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+		img.Set(i, i, color.NRGBA{R: 0xff, A: 0x80})
+		frames[i] = img
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, &APNG{Image: frames, Delay: []Delay{{1, 10}, {1, 10}, {1, 3}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the default image is the first frame, readable by image/png
+	first, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(color.NRGBA{R: 0xff, A: 0x80}, color.NRGBAModel.Convert(first.At(0, 0))); diff != "" {
+		t.Errorf("pixel mismatch (-want +got):\n%s", diff)
+	}
+
+	var names []string
+	var seq []uint32
+	r := bytes.NewReader(buf.Bytes()[len(signature):])
+	for {
+		var n uint32
+		if err = binary.Read(r, binary.BigEndian, &n); errors.Is(err, io.EOF) {
+			break
+		}
+		chunk := make([]byte, 4+n+4)
+		if _, err = io.ReadFull(r, chunk); err != nil {
+			t.Fatal(err)
+		}
+		if crc32.ChecksumIEEE(chunk[:4+n]) != binary.BigEndian.Uint32(chunk[4+n:]) {
+			t.Errorf("chunk %s: crc mismatch", chunk[:4])
+		}
+
+		names = append(names, string(chunk[:4]))
+		switch string(chunk[:4]) {
+		case "acTL":
+			if cnt := binary.BigEndian.Uint32(chunk[4:]); cnt != 3 {
+				t.Errorf("expected num_frames: 3, got %d", cnt)
+			}
+		case "fcTL", "fdAT":
+			seq = append(seq, binary.BigEndian.Uint32(chunk[4:]))
+		}
+	}
+
+	if diff := cmp.Diff([]string{"IHDR", "acTL", "fcTL", "IDAT", "fcTL", "fdAT", "fcTL", "fdAT", "IEND"}, names); diff != "" {
+		t.Errorf("chunks mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]uint32{0, 1, 2, 3, 4}, seq); diff != "" {
+		t.Errorf("sequence mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncode_InvalidFrame(t *testing.T) {
+	testcases := []struct {
+		name string
+		apng *APNG
+	}{
+		{
+			name: "no frames",
+			apng: &APNG{},
+		},
+		{
+			name: "different sizes",
+			apng: &APNG{
+				Image: []image.Image{image.NewNRGBA(image.Rect(0, 0, 1, 1)), image.NewNRGBA(image.Rect(0, 0, 2, 1))},
+				Delay: []Delay{{1, 10}, {1, 10}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Encode(io.Discard, tc.apng); !errors.Is(err, ErrInvalidFrame) {
+				t.Errorf("expected error: %v, got: %v", ErrInvalidFrame, err)
+			}
+		})
+	}
+}
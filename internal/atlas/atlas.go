@@ -0,0 +1,67 @@
+package atlas
+
+// Atlas is the sprite sheet descriptor in TexturePacker JSON (hash) format, it's also loadable by Phaser.
+type Atlas struct {
+	Frames     map[string]Frame    `json:"frames"`               // Frames keyed by name
+	Animations map[string][]string `json:"animations,omitempty"` // Frame names of each animation (optional)
+	Meta       Meta                `json:"meta"`
+}
+
+// Frame describes a sprite in the sheet.
+type Frame struct {
+	Frame            Rect  `json:"frame"`            // Rectangle of the sprite in the sheet
+	Rotated          bool  `json:"rotated"`          // Always false, sprites are not rotated
+	Trimmed          bool  `json:"trimmed"`          // Whether the sprite is trimmed from SourceSize
+	SpriteSourceSize Rect  `json:"spriteSourceSize"` // Position of the trimmed sprite in the source
+	SourceSize       Size  `json:"sourceSize"`       // Size of the source before trimming
+	Pivot            Point `json:"pivot"`            // Pivot relative to SourceSize, (0, 0) is top-left, (1, 1) is bottom-right
+	Duration         int   `json:"duration"`         // Frame duration in milliseconds
+	Direction        int   `json:"direction"`        // Direction of the animation
+	Action           int   `json:"action"`           // Action of the animation
+}
+
+// Meta describes the sprite sheet image.
+type Meta struct {
+	App     string `json:"app"`
+	Version string `json:"version"`
+	Image   string `json:"image"`  // File name of the sheet image
+	Format  string `json:"format"` // Pixel format of the sheet image, e.g. RGBA8888
+	Size    Size   `json:"size"`   // Size of the sheet image
+	Scale   string `json:"scale"`
+}
+
+// Rect is a rectangle in pixels.
+type Rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Size is a size in pixels.
+type Size struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// Point is a normalized point.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// NewAtlas creates an Atlas with empty frames.
+func NewAtlas(image string, w, h int) (a Atlas) {
+	a.Frames = make(map[string]Frame)
+	a.Animations = make(map[string][]string)
+	a.Meta = Meta{
+		App:     "xgtool",
+		Version: "1.0",
+		Image:   image,
+		Format:  "RGBA8888",
+		Size:    Size{W: w, H: h},
+		Scale:   "1",
+	}
+
+	return
+}
@@ -0,0 +1,52 @@
+package atlas
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// Pack places rectangles of sizes into a sheet with shelf packing, padding is the space between rectangles.
+//
+// The rectangles are returned in the same order of sizes, and size is the size of sheet.
+func Pack(sizes []image.Point, padding int) (rects []image.Rectangle, size image.Point) {
+	rects = make([]image.Rectangle, len(sizes))
+	if len(sizes) == 0 {
+		return
+	}
+
+	// the sheet width is about the square root of total area, but not narrower than the widest rectangle.
+	var area, maxW int
+	for _, s := range sizes {
+		area += (s.X + padding) * (s.Y + padding)
+		maxW = max(maxW, s.X)
+	}
+	width := max(maxW, int(math.Ceil(math.Sqrt(float64(area)))))
+
+	// place the taller rectangles first, so the shelves are filled evenly.
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sizes[order[i]].Y > sizes[order[j]].Y
+	})
+
+	var x, y, shelf int // shelf is the height of current shelf
+	for _, i := range order {
+		s := sizes[i]
+		if x > 0 && x+s.X > width {
+			x, y = 0, y+shelf+padding
+			shelf = 0
+		}
+
+		rects[i] = image.Rect(x, y, x+s.X, y+s.Y)
+		size.X = max(size.X, x+s.X)
+		size.Y = max(size.Y, y+s.Y)
+
+		x += s.X + padding
+		shelf = max(shelf, s.Y)
+	}
+
+	return
+}
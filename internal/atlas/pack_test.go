@@ -0,0 +1,39 @@
+package atlas
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	sizes := []image.Point{{10, 5}, {3, 20}, {7, 7}, {1, 1}, {0, 0}, {12, 2}, {4, 9}}
+
+	rects, size := Pack(sizes, 1)
+
+	if len(rects) != len(sizes) {
+		t.Fatalf("expected len(rects): %d, got %d", len(sizes), len(rects))
+	}
+
+	sheet := image.Rectangle{Max: size}
+	for i, r := range rects {
+		if r.Size() != sizes[i] {
+			t.Errorf("rect %d: expected size: %v, got %v", i, sizes[i], r.Size())
+		}
+		if !r.In(sheet) {
+			t.Errorf("rect %d: %v is out of sheet %v", i, r, sheet)
+		}
+		for j := i + 1; j < len(rects); j++ {
+			if r.Overlaps(rects[j]) {
+				t.Errorf("rect %d: %v overlaps rect %d: %v", i, r, j, rects[j])
+			}
+		}
+	}
+}
+
+func TestPack_Empty(t *testing.T) {
+	rects, size := Pack(nil, 1)
+
+	if len(rects) != 0 || size != (image.Point{}) {
+		t.Errorf("expected empty result, got %v, %v", rects, size)
+	}
+}
@@ -0,0 +1,92 @@
+package bmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+)
+
+const (
+	fileHeaderSize = 14
+	infoHeaderSize = 108 // BITMAPV4HEADER
+)
+
+type fileHeader struct {
+	Magic    [2]byte
+	Size     uint32
+	_        uint32
+	PixelOff uint32
+}
+
+// infoHeader is BITMAPV4HEADER, the color masks are required for the alpha channel.
+type infoHeader struct {
+	Size        uint32
+	Width       int32
+	Height      int32
+	Planes      uint16
+	BitCount    uint16
+	Compression uint32
+	ImageSize   uint32
+	XPerMeter   int32
+	YPerMeter   int32
+	ColorsUsed  uint32
+	ColorsImp   uint32
+	RedMask     uint32
+	GreenMask   uint32
+	BlueMask    uint32
+	AlphaMask   uint32
+	CSType      uint32
+	_           [36 + 12]byte // endpoints and gamma, unused for sRGB
+}
+
+// Encode writes img to w in 32 bits BMP format with alpha channel.
+func Encode(w io.Writer, img image.Image) (err error) {
+	b := img.Bounds()
+	size := uint32(b.Dx() * b.Dy() * 4)
+
+	fh := fileHeader{
+		Magic:    [2]byte{'B', 'M'},
+		Size:     fileHeaderSize + infoHeaderSize + size,
+		PixelOff: fileHeaderSize + infoHeaderSize,
+	}
+	ih := infoHeader{
+		Size:        infoHeaderSize,
+		Width:       int32(b.Dx()),
+		Height:      int32(b.Dy()), // positive height for bottom-up rows
+		Planes:      1,
+		BitCount:    32,
+		Compression: 3, // BI_BITFIELDS
+		ImageSize:   size,
+		XPerMeter:   2835, // 72 DPI
+		YPerMeter:   2835,
+		RedMask:     0x00ff0000,
+		GreenMask:   0x0000ff00,
+		BlueMask:    0x000000ff,
+		AlphaMask:   0xff000000,
+		CSType:      0x73524742, // "sRGB"
+	}
+
+	bw := bufio.NewWriter(w)
+	if err = binary.Write(bw, binary.LittleEndian, fh); err != nil {
+		return
+	}
+	if err = binary.Write(bw, binary.LittleEndian, ih); err != nil {
+		return
+	}
+
+	row := make([]byte, b.Dx()*4)
+	for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			i := (x - b.Min.X) * 4
+			row[i], row[i+1], row[i+2], row[i+3] = c.B, c.G, c.R, c.A
+		}
+		if _, err = bw.Write(row); err != nil {
+			return
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,47 @@
+package bmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncode(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.NRGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff})
+	img.Set(1, 1, color.NRGBA{R: 0xff, A: 0x80})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() != fileHeaderSize+infoHeaderSize+2*2*4 {
+		t.Fatalf("expected len: %d, got %d", fileHeaderSize+infoHeaderSize+2*2*4, buf.Len())
+	}
+
+	var fh fileHeader
+	var ih infoHeader
+	_ = binary.Read(&buf, binary.LittleEndian, &fh)
+	_ = binary.Read(&buf, binary.LittleEndian, &ih)
+
+	if fh.Magic != [2]byte{'B', 'M'} || int(fh.Size) != fileHeaderSize+infoHeaderSize+2*2*4 || fh.PixelOff != fileHeaderSize+infoHeaderSize {
+		t.Errorf("unexpected file header: %+v", fh)
+	}
+	if ih.Width != 2 || ih.Height != 2 || ih.BitCount != 32 {
+		t.Errorf("unexpected info header: %+v", ih)
+	}
+
+	// rows are bottom-up, pixels are BGRA
+	expect := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x80,
+		0x30, 0x20, 0x10, 0xff, 0x00, 0x00, 0x00, 0x00,
+	}
+	if diff := cmp.Diff(expect, buf.Bytes()); diff != "" {
+		t.Errorf("pixels mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -92,3 +92,82 @@ func makeAppend(data *byte, cnt int32, buf *bytes.Buffer) (app []byte, err error
 
 	return app, nil
 }
+
+// Encode to Run-Length Encoding, the reverse of Decode.
+//
+// Each run is written with the smallest flag that fits: zero runs as 0xc?/0xd?/0xe?, repeat runs as 0x8?/0x9?/0xa?,
+// and everything else as literal runs 0x0?/0x1?/0x2?.
+func Encode(decoded []byte) (encoded []byte) {
+	encoded = make([]byte, 0, len(decoded)/2)
+
+	var lit int // start of pending literal bytes
+	for i := 0; i < len(decoded); {
+		n := runLength(decoded[i:])
+
+		if !useRun(decoded[i], n, i > lit) {
+			i += n
+			continue
+		}
+
+		encoded = appendLiteral(encoded, decoded[lit:i])
+		encoded = appendRun(encoded, decoded[i], n)
+		i += n
+		lit = i
+	}
+
+	return appendLiteral(encoded, decoded[lit:])
+}
+
+// maxCnt is the largest count that can be stored in a flag with 2 extra bytes.
+const maxCnt = 0xfffff
+
+func runLength(b []byte) (n int) {
+	for n = 1; n < len(b) && n < maxCnt && b[n] == b[0]; n++ {
+	}
+
+	return
+}
+
+// useRun reports whether the run is shorter encoded as a run than appended to the pending literal bytes.
+func useRun(b byte, n int, pending bool) bool {
+	if b == 0 {
+		return n >= 2 || !pending
+	}
+
+	return n >= 3 || (n == 2 && !pending)
+}
+
+func appendLiteral(encoded, lit []byte) []byte {
+	for len(lit) > 0 {
+		n := min(len(lit), maxCnt)
+		encoded = appendFlag(encoded, 0x00, n)
+		encoded = append(encoded, lit[:n]...)
+		lit = lit[n:]
+	}
+
+	return encoded
+}
+
+func appendRun(encoded []byte, b byte, n int) []byte {
+	if b == 0 {
+		return appendFlag(encoded, 0xc0, n)
+	}
+
+	// The repeated byte is placed right after the first byte of the flag, before the rest of the count.
+	flag := appendFlag(nil, 0x80, n)
+	encoded = append(encoded, flag[0], b)
+
+	return append(encoded, flag[1:]...)
+}
+
+// appendFlag appends the flag byte with the count, the flag should be 0x00, 0x80 or 0xc0.
+func appendFlag(encoded []byte, flag byte, cnt int) []byte {
+	switch {
+	case cnt <= 0xf:
+		return append(encoded, flag|byte(cnt))
+	case cnt <= 0xfff:
+		return append(encoded, flag|0x10|byte(cnt>>8), byte(cnt))
+	default:
+		return append(encoded, flag|0x20|byte(cnt>>16), byte(cnt>>8), byte(cnt))
+	}
+}
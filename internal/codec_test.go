@@ -117,6 +117,103 @@ func TestDecode_InvalidFlag(t *testing.T) {
 	}
 }
 
+func TestEncode(t *testing.T) {
+	testcases := []struct {
+		name    string
+		decoded []byte
+		encoded []byte
+	}{
+		{
+			name:    "empty",
+			decoded: nil,
+			encoded: []byte{},
+		},
+		{
+			name:    "literal 1 byte",
+			decoded: []byte{0xaa},
+			encoded: []byte{0x01, 0xaa},
+		},
+		{
+			name:    "literal 3 bytes",
+			decoded: []byte{0xaa, 0xbb, 0xaa},
+			encoded: []byte{0x03, 0xaa, 0xbb, 0xaa},
+		},
+		{
+			name:    "repeat 1 byte 2 times",
+			decoded: []byte{0xaa, 0xaa},
+			encoded: []byte{0x82, 0xaa},
+		},
+		{
+			name:    "repeat 1 byte 4078 times",
+			decoded: bytes.Repeat([]byte{0xaa}, 0x0f*0x100+0xee),
+			encoded: []byte{0x9f, 0xaa, 0xee},
+		},
+		{
+			name:    "repeat 1 byte 74291 times",
+			decoded: bytes.Repeat([]byte{0xaa}, 0x01*0x10000+0x22*0x100+0x33),
+			encoded: []byte{0xa1, 0xaa, 0x22, 0x33},
+		},
+		{
+			name:    "repeat 1 alpha byte",
+			decoded: []byte{0x00},
+			encoded: []byte{0xc1},
+		},
+		{
+			name:    "repeat 4078 alpha bytes",
+			decoded: bytes.Repeat([]byte{0x00}, 0x0f*0x100+0xee),
+			encoded: []byte{0xdf, 0xee},
+		},
+		{
+			name:    "repeat 74291 alpha bytes",
+			decoded: bytes.Repeat([]byte{0x00}, 0x01*0x10000+0x22*0x100+0x33),
+			encoded: []byte{0xe1, 0x22, 0x33},
+		},
+		{
+			name:    "repeat more than 0xfffff bytes",
+			decoded: bytes.Repeat([]byte{0xaa}, 0xfffff+1),
+			encoded: []byte{0xaf, 0xaa, 0xff, 0xff, 0x01, 0xaa},
+		},
+		{
+			name:    "short repeat inside literal",
+			decoded: []byte{0x01, 0x02, 0x02, 0x03},
+			encoded: []byte{0x04, 0x01, 0x02, 0x02, 0x03},
+		},
+		{
+			name:    "mixed runs",
+			decoded: []byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x03, 0x03, 0x03},
+			encoded: []byte{0x02, 0x01, 0x02, 0xc3, 0x83, 0x03},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := Encode(tc.decoded)
+
+			if diff := cmp.Diff(tc.encoded, encoded); diff != "" {
+				t.Errorf("encoded mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func FuzzEncode(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0xaa, 0xaa, 0x00, 0x01})
+	f.Add(bytes.Repeat([]byte{0x00}, 0x1000))
+	f.Add(append(bytes.Repeat([]byte{0xaa}, 0x100), 0x00, 0x01, 0x02))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded, err := Decode(Encode(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !bytes.Equal(data, decoded) {
+			t.Errorf("round-trip mismatch: len(want)=%d, len(got)=%d", len(data), len(decoded))
+		}
+	})
+}
+
 func BenchmarkDecode_RepeatSingleByte(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_, _ = Decode([]byte{0xaf, 0xaa, 0xff, 0xff})
@@ -128,3 +225,33 @@ func BenchmarkDecode_RepeatAlphaByte(b *testing.B) {
 		_, _ = Decode([]byte{0xef, 0xff, 0xff})
 	}
 }
+
+func BenchmarkEncode_RepeatSingleByte(b *testing.B) {
+	data := bytes.Repeat([]byte{0xaa}, 0xfffff)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = Encode(data)
+	}
+}
+
+func BenchmarkEncode_RepeatAlphaByte(b *testing.B) {
+	data := bytes.Repeat([]byte{0x00}, 0xfffff)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = Encode(data)
+	}
+}
+
+func BenchmarkEncode_Literal(b *testing.B) {
+	data := make([]byte, 0xfffff)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = Encode(data)
+	}
+}
@@ -0,0 +1,191 @@
+package ldtk
+
+// LayerType is the type of layer.
+type LayerType string
+
+const (
+	IntGrid   LayerType = "IntGrid"
+	Entities  LayerType = "Entities"
+	Tiles     LayerType = "Tiles"
+	AutoLayer LayerType = "AutoLayer"
+)
+
+// LayerDef is the definition of layer.
+type LayerDef struct {
+	Type                  LayerType         `json:"__type"`                // Same as LayerType, for the readers
+	Identifier            string            `json:"identifier"`            // User defined unique identifier
+	LayerType             LayerType         `json:"type"`                  // IntGrid, Entities, Tiles or AutoLayer
+	UID                   int               `json:"uid"`                   // Unique ID
+	GridSize              int               `json:"gridSize"`              // Width and height of grid cells in pixels
+	DisplayOpacity        float64           `json:"displayOpacity"`        // Opacity of the layer (0 to 1)
+	PxOffsetX             int               `json:"pxOffsetX"`             // X offset of the layer in pixels
+	PxOffsetY             int               `json:"pxOffsetY"`             // Y offset of the layer in pixels
+	IntGridValues         []IntGridValueDef `json:"intGridValues"`         // Values of IntGrid layer
+	TilesetDefUID         *int              `json:"tilesetDefUid"`         // Tile set of Tiles layer (optional)
+	TilePivotX            float64           `json:"tilePivotX"`            // X pivot of tiles larger than the cells (0 to 1)
+	TilePivotY            float64           `json:"tilePivotY"`            // Y pivot of tiles larger than the cells (0 to 1)
+	RequiredTags          []string          `json:"requiredTags"`          // Only the entities with these tags can be placed
+	ExcludedTags          []string          `json:"excludedTags"`          // The entities with these tags can't be placed
+	AutoRuleGroups        []any             `json:"autoRuleGroups"`        // Always empty
+	IntGridValuesGroups   []any             `json:"intGridValuesGroups"`   // Always empty
+	UIFilterTags          []string          `json:"uiFilterTags"`          // Tags to filter the entities in editor
+	HideInList            bool              `json:"hideInList"`            // Hide the layer in the layer list
+	RenderInWorldView     bool              `json:"renderInWorldView"`     // Render the layer in the world view
+	CanSelectWhenInactive bool              `json:"canSelectWhenInactive"` // Select the layer content in other layers
+}
+
+// IntGridValueDef is a value of IntGrid layer, Tile is the icon in editor (optional).
+type IntGridValueDef struct {
+	Value      int          `json:"value"`      // Value, starting from 1
+	Identifier string       `json:"identifier"` // User defined unique identifier (optional)
+	Color      string       `json:"color"`      // Hex-formatted color (#RRGGBB)
+	Tile       *TilesetRect `json:"tile"`       // (optional)
+	GroupUID   int          `json:"groupUid"`   // Group of value, 0 is no group
+}
+
+// NewLayerDef creates a LayerDef.
+func NewLayerDef(uid int, identifier string, typ LayerType, gridSize int) (ld LayerDef) {
+	ld.Type, ld.LayerType = typ, typ
+	ld.Identifier = identifier
+	ld.UID = uid
+	ld.GridSize = gridSize
+	ld.DisplayOpacity = 1
+	ld.IntGridValues = []IntGridValueDef{}
+	ld.RequiredTags, ld.ExcludedTags, ld.UIFilterTags = []string{}, []string{}, []string{}
+	ld.AutoRuleGroups, ld.IntGridValuesGroups = []any{}, []any{}
+	ld.RenderInWorldView = true
+	ld.CanSelectWhenInactive = true
+
+	return
+}
+
+// EntityDef is the definition of entity.
+type EntityDef struct {
+	Identifier     string       `json:"identifier"`       // User defined unique identifier
+	UID            int          `json:"uid"`              // Unique ID
+	Tags           []string     `json:"tags"`             // Array of tags
+	Width          int          `json:"width"`            // Default width in pixels
+	Height         int          `json:"height"`           // Default height in pixels
+	PivotX         float64      `json:"pivotX"`           // X pivot (0 to 1)
+	PivotY         float64      `json:"pivotY"`           // Y pivot (0 to 1)
+	Color          string       `json:"color"`            // Hex-formatted color (#RRGGBB)
+	RenderMode     string       `json:"renderMode"`       // Rectangle, Ellipse, Tile or Cross
+	TileRenderMode string       `json:"tileRenderMode"`   // Cover, FitInside, Repeat, Stretch, FullSizeCropped, FullSizeUncropped or NineSlice
+	TilesetID      *int         `json:"tilesetId"`        // Tile set of Tile (optional)
+	TileRect       *TilesetRect `json:"tileRect"`         // Tile of Tile render mode (optional)
+	TileOpacity    float64      `json:"tileOpacity"`      // Opacity of tile (0 to 1)
+	FillOpacity    float64      `json:"fillOpacity"`      // Opacity of the fill of shape (0 to 1)
+	LineOpacity    float64      `json:"lineOpacity"`      // Opacity of the outline of shape (0 to 1)
+	ShowName       bool         `json:"showName"`         // Show the identifier in editor
+	LimitScope     string       `json:"limitScope"`       // PerLayer, PerLevel or PerWorld
+	LimitBehavior  string       `json:"limitBehavior"`    // DiscardOldOnes, PreventAdding or MoveLastOne
+	MaxCount       int          `json:"maxCount"`         // Max instances, 0 is unlimited
+	FieldDefs      []FieldDef   `json:"fieldDefs"`        // Array of FieldDef
+	NineSlice      []int        `json:"nineSliceBorders"` // Borders of NineSlice render mode, always empty
+}
+
+// NewEntityDef creates an EntityDef of w x h pixels.
+func NewEntityDef(uid int, identifier string, w, h int) (ed EntityDef) {
+	ed.Identifier = identifier
+	ed.UID = uid
+	ed.Tags = []string{}
+	ed.Width, ed.Height = w, h
+	ed.Color = "#94D9B3"
+	ed.RenderMode = "Rectangle"
+	ed.TileRenderMode = "FitInside"
+	ed.TileOpacity, ed.FillOpacity, ed.LineOpacity = 1, 0.08, 1
+	ed.LimitScope = "PerLevel"
+	ed.LimitBehavior = "MoveLastOne"
+	ed.FieldDefs = []FieldDef{}
+	ed.NineSlice = []int{}
+
+	return
+}
+
+// FieldType is the type of custom field, only integers are used by the exported projects.
+type FieldType string
+
+const FieldInt FieldType = "F_Int"
+
+// FieldDef is the definition of custom field of entities or levels.
+type FieldDef struct {
+	Identifier        string    `json:"identifier"`        // User defined unique identifier
+	Type              string    `json:"__type"`            // Human readable type, such as Int
+	FieldType         FieldType `json:"type"`              // Internal type, such as F_Int
+	UID               int       `json:"uid"`               // Unique ID
+	IsArray           bool      `json:"isArray"`           // The field is an array
+	CanBeNull         bool      `json:"canBeNull"`         // The value can be null
+	EditorDisplayMode string    `json:"editorDisplayMode"` // Hidden, ValueOnly, NameAndValue, ...
+	EditorDisplayPos  string    `json:"editorDisplayPos"`  // Above, Center or Beneath
+	DefaultOverride   *Value    `json:"defaultOverride"`   // Default value (optional)
+}
+
+// NewIntFieldDef creates a FieldDef of integer, def is its default value.
+func NewIntFieldDef(uid int, identifier string, def int) FieldDef {
+	return FieldDef{
+		Identifier:        identifier,
+		Type:              "Int",
+		FieldType:         FieldInt,
+		UID:               uid,
+		EditorDisplayMode: "NameAndValue",
+		EditorDisplayPos:  "Above",
+		DefaultOverride:   IntValue(def),
+	}
+}
+
+// Value is the value of field in editor, such as {"id": "V_Int", "params": [1]}.
+type Value struct {
+	ID     string `json:"id"`
+	Params []any  `json:"params"`
+}
+
+// IntValue creates a Value of integer.
+func IntValue(v int) *Value {
+	return &Value{ID: "V_Int", Params: []any{v}}
+}
+
+// TilesetDef is the definition of tile set, which is an image split into a grid.
+type TilesetDef struct {
+	CWid         int      `json:"__cWid"`          // Grid-based width
+	CHei         int      `json:"__cHei"`          // Grid-based height
+	Identifier   string   `json:"identifier"`      // User defined unique identifier
+	UID          int      `json:"uid"`             // Unique ID
+	RelPath      string   `json:"relPath"`         // Path of image, relative to the project file
+	PxWid        int      `json:"pxWid"`           // Image width in pixels
+	PxHei        int      `json:"pxHei"`           // Image height in pixels
+	TileGridSize int      `json:"tileGridSize"`    // Width and height of grid cells in pixels
+	Spacing      int      `json:"spacing"`         // Space between tiles in pixels
+	Padding      int      `json:"padding"`         // Distance from the image borders in pixels
+	Tags         []string `json:"tags"`            // Array of tags
+	EnumTags     []any    `json:"enumTags"`        // Always empty
+	CustomData   []any    `json:"customData"`      // Always empty
+	Selections   []any    `json:"savedSelections"` // Always empty
+}
+
+// NewTilesetDef creates a TilesetDef of the image in w x h pixels, the cells are spacing pixels apart.
+func NewTilesetDef(uid int, identifier, relPath string, w, h, gridSize, spacing int) TilesetDef {
+	return TilesetDef{
+		CWid:         (w + spacing) / (gridSize + spacing),
+		CHei:         (h + spacing) / (gridSize + spacing),
+		Identifier:   identifier,
+		UID:          uid,
+		RelPath:      relPath,
+		PxWid:        w,
+		PxHei:        h,
+		TileGridSize: gridSize,
+		Spacing:      spacing,
+		Tags:         []string{},
+		EnumTags:     []any{},
+		CustomData:   []any{},
+		Selections:   []any{},
+	}
+}
+
+// TilesetRect is a rectangle in the image of tile set, it doesn't need to be aligned to the grid.
+type TilesetRect struct {
+	TilesetUID int `json:"tilesetUid"`
+	X          int `json:"x"`
+	Y          int `json:"y"`
+	W          int `json:"w"`
+	H          int `json:"h"`
+}
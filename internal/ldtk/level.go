@@ -0,0 +1,158 @@
+package ldtk
+
+// Level is a level of project, the position and size are in pixels.
+type Level struct {
+	Identifier     string          `json:"identifier"`     // User defined unique identifier
+	IID            string          `json:"iid"`            // Unique instance identifier
+	UID            int             `json:"uid"`            // Unique ID
+	WorldX         int             `json:"worldX"`         // X position in the world
+	WorldY         int             `json:"worldY"`         // Y position in the world
+	WorldDepth     int             `json:"worldDepth"`     // Index of the world layer
+	PxWid          int             `json:"pxWid"`          // Width in pixels
+	PxHei          int             `json:"pxHei"`          // Height in pixels
+	BgColor        string          `json:"__bgColor"`      // Background color, the level's or the project's default
+	BgPivotX       float64         `json:"bgPivotX"`       // X pivot of the background image (0 to 1)
+	BgPivotY       float64         `json:"bgPivotY"`       // Y pivot of the background image (0 to 1)
+	FieldInstances []FieldInstance `json:"fieldInstances"` // Array of FieldInstance
+	LayerInstances []LayerInstance `json:"layerInstances"` // Array of LayerInstance, in the same order as the definitions
+	Neighbours     []any           `json:"__neighbours"`   // Neighbour levels, always empty
+}
+
+// NewLevel creates an empty Level of w x h pixels.
+func NewLevel(uid int, identifier string, w, h int) Level {
+	return Level{
+		Identifier:     identifier,
+		IID:            NewIID(),
+		UID:            uid,
+		PxWid:          w,
+		PxHei:          h,
+		BgColor:        "#696A79",
+		BgPivotX:       0.5,
+		BgPivotY:       0.5,
+		FieldInstances: []FieldInstance{},
+		LayerInstances: []LayerInstance{},
+		Neighbours:     []any{},
+	}
+}
+
+// LayerInstance is a layer of level.
+type LayerInstance struct {
+	Identifier      string           `json:"__identifier"`     // Identifier of the definition
+	Type            LayerType        `json:"__type"`           // IntGrid, Entities, Tiles or AutoLayer
+	CWid            int              `json:"__cWid"`           // Grid-based width
+	CHei            int              `json:"__cHei"`           // Grid-based height
+	GridSize        int              `json:"__gridSize"`       // Width and height of grid cells in pixels
+	Opacity         float64          `json:"__opacity"`        // Opacity of the layer (0 to 1)
+	PxTotalOffsetX  int              `json:"__pxTotalOffsetX"` // Total X offset of the layer and its definition
+	PxTotalOffsetY  int              `json:"__pxTotalOffsetY"` // Total Y offset of the layer and its definition
+	TilesetDefUID   *int             `json:"__tilesetDefUid"`  // Tile set of Tiles layer (optional)
+	TilesetRelPath  *string          `json:"__tilesetRelPath"` // Path of the tile set image (optional)
+	IID             string           `json:"iid"`              // Unique instance identifier
+	LevelID         int              `json:"levelId"`          // UID of the level
+	LayerDefUID     int              `json:"layerDefUid"`      // UID of the definition
+	PxOffsetX       int              `json:"pxOffsetX"`        // X offset of the layer in pixels
+	PxOffsetY       int              `json:"pxOffsetY"`        // Y offset of the layer in pixels
+	Visible         bool             `json:"visible"`          // Layer is visible
+	Seed            int              `json:"seed"`             // Random seed of auto layer rules
+	IntGridCSV      []int            `json:"intGridCsv"`       // Values of IntGrid layer, row by row, 0 is empty
+	GridTiles       []TileInstance   `json:"gridTiles"`        // Tiles of Tiles layer
+	AutoLayerTiles  []TileInstance   `json:"autoLayerTiles"`   // Tiles generated by auto layer rules
+	EntityInstances []EntityInstance `json:"entityInstances"`  // Entities of Entities layer
+	OptionalRules   []int            `json:"optionalRules"`    // Enabled optional rules, always empty
+}
+
+// NewLayerInstance creates an empty LayerInstance of def in level, its grid covers the level.
+func NewLayerInstance(def LayerDef, level Level) LayerInstance {
+	cWid := (level.PxWid + def.GridSize - 1) / def.GridSize
+	cHei := (level.PxHei + def.GridSize - 1) / def.GridSize
+
+	return LayerInstance{
+		Identifier:      def.Identifier,
+		Type:            def.LayerType,
+		CWid:            cWid,
+		CHei:            cHei,
+		GridSize:        def.GridSize,
+		Opacity:         def.DisplayOpacity,
+		PxTotalOffsetX:  def.PxOffsetX,
+		PxTotalOffsetY:  def.PxOffsetY,
+		TilesetDefUID:   def.TilesetDefUID,
+		IID:             NewIID(),
+		LevelID:         level.UID,
+		LayerDefUID:     def.UID,
+		Visible:         true,
+		IntGridCSV:      []int{},
+		GridTiles:       []TileInstance{},
+		AutoLayerTiles:  []TileInstance{},
+		EntityInstances: []EntityInstance{},
+		OptionalRules:   []int{},
+	}
+}
+
+// TileInstance is a tile of Tiles layer.
+type TileInstance struct {
+	Px  [2]int  `json:"px"`  // Position in the layer in pixels
+	Src [2]int  `json:"src"` // Position in the tile set image in pixels
+	F   int     `json:"f"`   // Flip bits, 1 is X and 2 is Y
+	T   int     `json:"t"`   // Tile ID in the tile set
+	D   []int   `json:"d"`   // Internal data, the coordinate ID of cell
+	A   float64 `json:"a"`   // Alpha (0 to 1)
+}
+
+// EntityInstance is an entity of Entities layer.
+type EntityInstance struct {
+	Identifier     string          `json:"__identifier"`   // Identifier of the definition
+	Grid           [2]int          `json:"__grid"`         // Grid-based position
+	Pivot          [2]float64      `json:"__pivot"`        // Pivot of the definition
+	Tags           []string        `json:"__tags"`         // Tags of the definition
+	Tile           *TilesetRect    `json:"__tile"`         // Tile of the definition (optional)
+	SmartColor     string          `json:"__smartColor"`   // Color of the definition
+	WorldX         int             `json:"__worldX"`       // X position in the world
+	WorldY         int             `json:"__worldY"`       // Y position in the world
+	IID            string          `json:"iid"`            // Unique instance identifier
+	DefUID         int             `json:"defUid"`         // UID of the definition
+	Px             [2]int          `json:"px"`             // Position of the pivot in the layer in pixels
+	Width          int             `json:"width"`          // Width in pixels
+	Height         int             `json:"height"`         // Height in pixels
+	FieldInstances []FieldInstance `json:"fieldInstances"` // Array of FieldInstance
+}
+
+// NewEntityInstance creates an EntityInstance of def at (x, y) pixels in layer.
+func NewEntityInstance(def EntityDef, layer LayerInstance, level Level, x, y int) EntityInstance {
+	return EntityInstance{
+		Identifier:     def.Identifier,
+		Grid:           [2]int{x / layer.GridSize, y / layer.GridSize},
+		Pivot:          [2]float64{def.PivotX, def.PivotY},
+		Tags:           def.Tags,
+		Tile:           def.TileRect,
+		SmartColor:     def.Color,
+		WorldX:         level.WorldX + x,
+		WorldY:         level.WorldY + y,
+		IID:            NewIID(),
+		DefUID:         def.UID,
+		Px:             [2]int{x, y},
+		Width:          def.Width,
+		Height:         def.Height,
+		FieldInstances: []FieldInstance{},
+	}
+}
+
+// FieldInstance is the value of custom field.
+type FieldInstance struct {
+	Identifier       string   `json:"__identifier"`     // Identifier of the definition
+	Type             string   `json:"__type"`           // Human readable type, such as Int
+	Value            any      `json:"__value"`          // Value for the readers
+	Tile             any      `json:"__tile"`           // Tile of the value, always null
+	DefUID           int      `json:"defUid"`           // UID of the definition
+	RealEditorValues []*Value `json:"realEditorValues"` // Value in editor
+}
+
+// NewIntFieldInstance creates a FieldInstance of def with integer v.
+func NewIntFieldInstance(def FieldDef, v int) FieldInstance {
+	return FieldInstance{
+		Identifier:       def.Identifier,
+		Type:             def.Type,
+		Value:            v,
+		DefUID:           def.UID,
+		RealEditorValues: []*Value{IntValue(v)},
+	}
+}
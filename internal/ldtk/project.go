@@ -0,0 +1,134 @@
+package ldtk
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// Version is the version of LDtk JSON format.
+const Version = "1.5.3"
+
+// Project is the root of LDtk project file (.ldtk).
+//
+// Only the fields needed by the exported projects are defined, LDtk fills the others with their default values.
+//
+// Ref: https://ldtk.io/json/
+type Project struct {
+	Header              Header  `json:"__header__"`
+	IID                 string  `json:"iid"`                 // Unique instance identifier
+	JSONVersion         string  `json:"jsonVersion"`         // File format version
+	NextUID             int     `json:"nextUid"`             // Next unique ID of definitions
+	WorldLayout         string  `json:"worldLayout"`         // Free, GridVania, LinearHorizontal or LinearVertical
+	WorldGridWidth      int     `json:"worldGridWidth"`      // Width of the world grid in pixels
+	WorldGridHeight     int     `json:"worldGridHeight"`     // Height of the world grid in pixels
+	DefaultGridSize     int     `json:"defaultGridSize"`     // Default grid size for new layers
+	DefaultLevelWidth   int     `json:"defaultLevelWidth"`   // Default width for new levels
+	DefaultLevelHeight  int     `json:"defaultLevelHeight"`  // Default height for new levels
+	DefaultPivotX       float64 `json:"defaultPivotX"`       // Default X pivot (0 to 1) for new entities
+	DefaultPivotY       float64 `json:"defaultPivotY"`       // Default Y pivot (0 to 1) for new entities
+	DefaultEntityWidth  int     `json:"defaultEntityWidth"`  // Default width for new entities
+	DefaultEntityHeight int     `json:"defaultEntityHeight"` // Default height for new entities
+	BgColor             string  `json:"bgColor"`             // Project background color
+	DefaultLevelBgColor string  `json:"defaultLevelBgColor"` // Default background color of levels
+	ExternalLevels      bool    `json:"externalLevels"`      // If true, levels are saved in separate files
+	MinifyJSON          bool    `json:"minifyJson"`          // If true, the JSON is minified
+	Defs                Defs    `json:"defs"`                // Definitions of layers, entities and tile sets
+	Levels              []Level `json:"levels"`              // Array of Level
+	Worlds              []any   `json:"worlds"`              // Only used with the multi-worlds flag, always empty
+	TOC                 []any   `json:"toc"`                 // Table of content of entities, always empty
+	Flags               []any   `json:"flags"`               // Advanced flags, always empty
+	CustomCommands      []any   `json:"customCommands"`      // Commands run on saving, always empty
+}
+
+// Header is the metadata of project file.
+type Header struct {
+	FileType   string `json:"fileType"`
+	App        string `json:"app"`
+	Doc        string `json:"doc"`
+	Schema     string `json:"schema"`
+	AppAuthor  string `json:"appAuthor"`
+	AppVersion string `json:"appVersion"`
+	URL        string `json:"url"`
+}
+
+// Defs contains the definitions of project.
+type Defs struct {
+	Layers        []LayerDef   `json:"layers"`        // Array of LayerDef, the first one is on top
+	Entities      []EntityDef  `json:"entities"`      // Array of EntityDef
+	Tilesets      []TilesetDef `json:"tilesets"`      // Array of TilesetDef
+	Enums         []any        `json:"enums"`         // Always empty
+	ExternalEnums []any        `json:"externalEnums"` // Always empty
+	LevelFields   []FieldDef   `json:"levelFields"`   // Array of FieldDef of levels
+}
+
+// NewProject creates an empty Project, the size of grid and levels are in pixels.
+func NewProject(gridSize, levelWidth, levelHeight int) (p Project) {
+	p.Header = Header{
+		FileType:   "LDtk Project JSON",
+		App:        "LDtk",
+		Doc:        "https://ldtk.io/json",
+		Schema:     "https://ldtk.io/files/JSON_SCHEMA.json",
+		AppAuthor:  "Sebastien 'deepnight' Benard",
+		AppVersion: Version,
+		URL:        "https://ldtk.io",
+	}
+	p.IID = NewIID()
+	p.JSONVersion = Version
+	p.NextUID = 1
+	p.WorldLayout = "Free"
+	p.WorldGridWidth, p.WorldGridHeight = levelWidth, levelHeight
+	p.DefaultGridSize = gridSize
+	p.DefaultLevelWidth, p.DefaultLevelHeight = levelWidth, levelHeight
+	p.DefaultEntityWidth, p.DefaultEntityHeight = gridSize, gridSize
+	p.BgColor = "#40465B"
+	p.DefaultLevelBgColor = "#696A79"
+	p.Defs = Defs{
+		Layers:        []LayerDef{},
+		Entities:      []EntityDef{},
+		Tilesets:      []TilesetDef{},
+		Enums:         []any{},
+		ExternalEnums: []any{},
+		LevelFields:   []FieldDef{},
+	}
+	p.Levels = []Level{}
+	p.Worlds, p.TOC, p.Flags, p.CustomCommands = []any{}, []any{}, []any{}, []any{}
+
+	return
+}
+
+// UID returns a new unique ID for definitions.
+func (p *Project) UID() (uid int) {
+	uid = p.NextUID
+	p.NextUID++
+
+	return
+}
+
+// NewIID returns a random UUID (version 4), which is the unique instance identifier of projects, levels, layers and
+// entities.
+func NewIID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Identifier converts s to a valid identifier, which starts with a letter or underscore, and has only letters, digits
+// and underscores. The invalid characters are replaced by underscores, and prefix is added if s doesn't start with a
+// letter or underscore.
+func Identifier(prefix, s string) string {
+	id := strings.Map(func(r rune) rune {
+		if r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, s)
+	if id == "" || id[0] >= '0' && id[0] <= '9' {
+		id = prefix + id
+	}
+
+	return id
+}
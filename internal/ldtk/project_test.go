@@ -0,0 +1,95 @@
+package ldtk
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIdentifier(t *testing.T) {
+	testcases := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{name: "valid", s: "Ground_1", want: "Ground_1"},
+		{name: "digit", s: "1000", want: "Map_1000"},
+		{name: "path", s: "0/1000-a", want: "Map_0_1000_a"},
+		{name: "unicode", s: "法蘭城", want: "___"},
+		{name: "empty", s: "", want: "Map_"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Identifier("Map_", tc.s); got != tc.want {
+				t.Errorf("Identifier() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewIID(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	a, b := NewIID(), NewIID()
+	if !re.MatchString(a) || !re.MatchString(b) {
+		t.Errorf("NewIID() = %q, %q, want UUID v4", a, b)
+	}
+	if a == b {
+		t.Errorf("NewIID() = %q twice", a)
+	}
+}
+
+func TestProject_JSON(t *testing.T) {
+	p := NewProject(16, 320, 160)
+	ld := NewLayerDef(p.UID(), "Collision", IntGrid, 16)
+	ld.IntGridValues = append(ld.IntGridValues, IntGridValueDef{Value: 1, Identifier: "blocked", Color: "#FF0000"})
+	ed := NewEntityDef(p.UID(), "Object", 8, 8)
+	ed.FieldDefs = append(ed.FieldDefs, NewIntFieldDef(p.UID(), "MapID", 0))
+	p.Defs.Layers = append(p.Defs.Layers, ld)
+	p.Defs.Entities = append(p.Defs.Entities, ed)
+
+	level := NewLevel(p.UID(), "Level_0", 320, 160)
+	layer := NewLayerInstance(ld, level)
+	layer.IntGridCSV = make([]int, layer.CWid*layer.CHei)
+	e := NewEntityInstance(ed, layer, level, 40, 20)
+	e.FieldInstances = append(e.FieldInstances, NewIntFieldInstance(ed.FieldDefs[0], 7))
+	layer.EntityInstances = append(layer.EntityInstances, e)
+	level.LayerInstances = append(level.LayerInstances, layer)
+	p.Levels = append(p.Levels, level)
+
+	if p.NextUID != 5 {
+		t.Errorf("NextUID = %d, want 5", p.NextUID)
+	}
+	if layer.CWid != 20 || layer.CHei != 10 || e.Grid != [2]int{2, 1} {
+		t.Errorf("unexpected grid: %dx%d, entity at %v", layer.CWid, layer.CHei, e.Grid)
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err = json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["jsonVersion"] != Version || got["__header__"].(map[string]any)["fileType"] != "LDtk Project JSON" {
+		t.Errorf("unexpected header: %s", b)
+	}
+
+	field := got["levels"].([]any)[0].(map[string]any)["layerInstances"].([]any)[0].(map[string]any)["entityInstances"].([]any)[0].(map[string]any)["fieldInstances"].([]any)[0]
+	want := map[string]any{
+		"__identifier":     "MapID",
+		"__type":           "Int",
+		"__value":          float64(7),
+		"__tile":           nil,
+		"defUid":           float64(3),
+		"realEditorValues": []any{map[string]any{"id": "V_Int", "params": []any{float64(7)}}},
+	}
+	if diff := cmp.Diff(want, field); diff != "" {
+		t.Errorf("field instance mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -66,6 +66,24 @@ func (m Matrix) Rotate() Matrix {
 	return dst
 }
 
+// RotateBack rotates the matrix 90 degrees clockwise, it undoes Rotate.
+func (m Matrix) RotateBack() Matrix {
+	dst := Matrix{
+		W:    m.H,
+		H:    m.W,
+		Data: make([]int, m.W*m.H),
+	}
+
+	// the cell (c, r) of dst is the cell (r, dst.W-1-c) of m, see Rotate
+	for r := 0; r < dst.H; r++ {
+		for c := 0; c < dst.W; c++ {
+			dst.Data[r*dst.W+c] = m.Data[(dst.W-1-c)*m.W+r]
+		}
+	}
+
+	return dst
+}
+
 // ToUint16Array converts the matrix to a uint16 array.
 //
 // Note: the matrix data is converted to uint16, so the data may be lost.
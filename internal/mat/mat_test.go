@@ -28,3 +28,11 @@ func TestMatrix_Rotate(t *testing.T) {
 		t.Errorf("Matrix.Rotate() mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestMatrix_RotateBack(t *testing.T) {
+	data := []int{1, 2, 3, 4, 5, 6}
+	mat, _ := NewMatrix(data, 2, 3)
+	if diff := cmp.Diff(mat, mat.Rotate().RotateBack()); diff != "" {
+		t.Errorf("Matrix.RotateBack() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,187 @@
+package tmx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// ErrUnsupportedEncoding is returned when the encoding of tile layer data is neither CSV nor Base64.
+	ErrUnsupportedEncoding = errors.New("unsupported encoding")
+	// ErrUnsupportedCompression is returned when the compression of tile layer data is not zlib, gzip or zstd.
+	ErrUnsupportedCompression = errors.New("unsupported compression")
+	// ErrInvalidData is returned when the tile layer data can't be decoded, or doesn't have the GIDs of every tile.
+	ErrInvalidData = errors.New("invalid data")
+)
+
+// Data is the GIDs of tile layer or chunk.
+//
+// It's an array in JSON by default (CSV encoding), or a base64 string of little-endian uint32 compressed by the
+// Compression of layer if the Encoding of layer is Base64, which is handled by Layer.MarshalJSON and
+// Layer.UnmarshalJSON.
+type Data []uint
+
+// Compression of base64-encoded tile layer data, empty means uncompressed.
+const (
+	Zlib = "zlib"
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// encodeData encodes GIDs in enc, CSV is split into rows of w GIDs (w <= 0 means one row), and Base64 is the
+// little-endian uint32 array compressed by compression.
+func encodeData(data Data, w int, enc Encoding, compression string) (s string, err error) {
+	switch enc {
+	case CSV, "":
+		if compression != "" {
+			return "", fmt.Errorf("%w: %s with csv", ErrUnsupportedCompression, compression)
+		}
+
+		return encodeCSV(data, w), nil
+	case Base64:
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedEncoding, enc)
+	}
+
+	raw := make([]byte, 4*len(data))
+	for i, gid := range data {
+		binary.LittleEndian.PutUint32(raw[4*i:], uint32(gid))
+	}
+
+	buf := new(bytes.Buffer)
+	var wc io.WriteCloser
+	switch compression {
+	case "":
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case Zlib:
+		wc = zlib.NewWriter(buf)
+	case Gzip:
+		wc = gzip.NewWriter(buf)
+	case Zstd:
+		if wc, err = zstd.NewWriter(buf); err != nil {
+			return
+		}
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCompression, compression)
+	}
+
+	if _, err = wc.Write(raw); err != nil {
+		return
+	}
+	if err = wc.Close(); err != nil {
+		return
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func encodeCSV(data Data, w int) string {
+	if w <= 0 {
+		w = len(data)
+	}
+
+	var sb strings.Builder
+	for i, gid := range data {
+		if i > 0 {
+			sb.WriteByte(',')
+			if i%w == 0 {
+				sb.WriteByte('\n')
+			}
+		}
+		sb.WriteString(strconv.FormatUint(uint64(gid), 10))
+	}
+
+	return sb.String()
+}
+
+// decodeData decodes GIDs of w*h tiles from the format of encodeData, white spaces around GIDs and base64 data are
+// ignored.
+func decodeData(s string, enc Encoding, compression string, w, h int) (data Data, err error) {
+	switch enc {
+	case CSV, "":
+		if data, err = decodeCSV(s); err != nil {
+			return
+		}
+
+		return data, checkSize(data, w, h)
+	case Base64:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, enc)
+	}
+
+	var raw []byte
+	if raw, err = base64.StdEncoding.DecodeString(strings.TrimSpace(s)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	var rc io.ReadCloser
+	switch compression {
+	case "":
+	case Zlib:
+		rc, err = zlib.NewReader(bytes.NewReader(raw))
+	case Gzip:
+		rc, err = gzip.NewReader(bytes.NewReader(raw))
+	case Zstd:
+		var d *zstd.Decoder
+		if d, err = zstd.NewReader(bytes.NewReader(raw)); err == nil {
+			rc = d.IOReadCloser()
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompression, compression)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	if rc != nil {
+		defer rc.Close()
+		if raw, err = io.ReadAll(rc); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+		}
+	}
+
+	if len(raw)%4 != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a multiple of 4", ErrInvalidData, len(raw))
+	}
+
+	data = make(Data, len(raw)/4)
+	for i := range data {
+		data[i] = uint(binary.LittleEndian.Uint32(raw[4*i:]))
+	}
+
+	return data, checkSize(data, w, h)
+}
+
+// checkSize checks data has a GID for each of w*h tiles.
+func checkSize(data Data, w, h int) error {
+	if len(data) != w*h {
+		return fmt.Errorf("%w: %d GIDs for %dx%d tiles", ErrInvalidData, len(data), w, h)
+	}
+
+	return nil
+}
+
+func decodeCSV(s string) (data Data, err error) {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+
+		var gid uint64
+		if gid, err = strconv.ParseUint(v, 10, 32); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+		}
+		data = append(data, uint(gid))
+	}
+
+	return
+}
@@ -1,5 +1,10 @@
 package tmx
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // DrawOrder of object group layer
 type DrawOrder string
 
@@ -31,7 +36,7 @@ type Layer struct {
 	Chunks           []Chunk    `json:"chunks,omitempty"`           // Array of chunks (optional). TileLayer only.
 	Class            string     `json:"class,omitempty"`            // The class of the layer (since 1.9, optional)
 	Compression      string     `json:"compression,omitempty"`      // `zlib`, `gzip`, `zstd` (since Tiled 1.3) or empty (default). TileLayer only.
-	Data             []uint     `json:"data,omitempty"`             // Array of unsigned int (GIDs) or base64-encoded data. TileLayer only.
+	Data             Data       `json:"data,omitempty"`             // Array of unsigned int (GIDs) or base64-encoded data. TileLayer only.
 	DrawOrder        DrawOrder  `json:"draworder,omitempty"`        // TopDown (default) or index. ObjectGroup only.
 	Encoding         Encoding   `json:"encoding,omitempty"`         // CSV (default) or Base64. TileLayer only.
 	Height           int        `json:"height,omitempty"`           // Row count. Same as map height for fixed-size maps. TileLayer only.
@@ -84,3 +89,102 @@ func NewObjectLayer(name string, id int, do DrawOrder) (l Layer) {
 
 	return
 }
+
+// layerAlias and chunkAlias have the fields of Layer and Chunk without their methods.
+type (
+	layerAlias Layer
+	chunkAlias Chunk
+)
+
+// layerJSON is Layer with Data and Chunks in the encoded form.
+type layerJSON struct {
+	layerAlias
+	Data   json.RawMessage `json:"data,omitempty"`
+	Chunks []chunkJSON     `json:"chunks,omitempty"`
+}
+
+type chunkJSON struct {
+	chunkAlias
+	Data json.RawMessage `json:"data"`
+}
+
+// MarshalJSON encodes l, Data and the data of Chunks are arrays, or base64 strings compressed by Compression if
+// Encoding is Base64.
+func (l Layer) MarshalJSON() (b []byte, err error) {
+	v := layerJSON{layerAlias: layerAlias(l)}
+	if len(l.Data) > 0 {
+		if v.Data, err = l.marshalData(l.Data); err != nil {
+			return nil, fmt.Errorf("layer %q: %w", l.Name, err)
+		}
+	}
+	for _, c := range l.Chunks {
+		cj := chunkJSON{chunkAlias: chunkAlias(c)}
+		if cj.Data, err = l.marshalData(c.Data); err != nil {
+			return nil, fmt.Errorf("layer %q: %w", l.Name, err)
+		}
+		v.Chunks = append(v.Chunks, cj)
+	}
+
+	return json.Marshal(v)
+}
+
+func (l Layer) marshalData(data Data) (json.RawMessage, error) {
+	if l.Encoding != Base64 {
+		if l.Compression != "" {
+			return nil, fmt.Errorf("%w: %s with csv", ErrUnsupportedCompression, l.Compression)
+		}
+
+		return json.Marshal([]uint(data))
+	}
+
+	s, err := encodeData(data, 0, l.Encoding, l.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON decodes l, Data and the data of Chunks can be arrays, or base64 strings compressed by Compression.
+func (l *Layer) UnmarshalJSON(b []byte) (err error) {
+	var v layerJSON
+	if err = json.Unmarshal(b, &v); err != nil {
+		return
+	}
+
+	*l = Layer(v.layerAlias)
+	if l.Data, err = l.unmarshalData(v.Data, l.Width, l.Height); err != nil {
+		return fmt.Errorf("layer %q: %w", l.Name, err)
+	}
+	for _, cj := range v.Chunks {
+		c := Chunk(cj.chunkAlias)
+		if c.Data, err = l.unmarshalData(cj.Data, c.Width, c.Height); err != nil {
+			return fmt.Errorf("layer %q: %w", l.Name, err)
+		}
+		l.Chunks = append(l.Chunks, c)
+	}
+
+	return
+}
+
+// unmarshalData decodes the GIDs of w*h tiles from an array or a base64 string.
+func (l Layer) unmarshalData(raw json.RawMessage, w, h int) (data Data, err error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return
+	}
+
+	if raw[0] != '"' {
+		if err = json.Unmarshal(raw, (*[]uint)(&data)); err != nil {
+			return
+		}
+
+		return data, checkSize(data, w, h)
+	}
+
+	var s string
+	if err = json.Unmarshal(raw, &s); err != nil {
+		return
+	}
+
+	return decodeData(s, Base64, l.Compression, w, h)
+}
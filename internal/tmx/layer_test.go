@@ -2,6 +2,7 @@ package tmx
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"testing"
 
@@ -75,3 +76,123 @@ func TestDecodeLayer(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeLayer_Base64(t *testing.T) {
+	data := Data{1, 2, 1, 2, 3, 1, 3, 1, 2, 2, 3, 3, 4, 4, 4, 1}
+
+	testcases := []struct {
+		name   string
+		data   string
+		expect Layer
+	}{
+		{
+			name: "zlib",
+			data: "testdata/layer_base64_zlib.json",
+			expect: Layer{
+				Compression: Zlib, Data: data, Encoding: Base64, Height: 4, ID: 1, Name: "zlib", Opacity: 1,
+				Type: TileLayer, Visible: true, Width: 4,
+			},
+		},
+		{
+			name: "gzip",
+			data: "testdata/layer_base64_gzip.json",
+			expect: Layer{
+				Compression: Gzip, Data: data, Encoding: Base64, Height: 4, ID: 1, Name: "gzip", Opacity: 1,
+				Type: TileLayer, Visible: true, Width: 4,
+			},
+		},
+		{
+			name: "chunks",
+			data: "testdata/layer_base64_chunks.json",
+			expect: Layer{
+				Chunks:   []Chunk{{Data: data, Height: 4, Width: 4, X: 0, Y: -4}},
+				Encoding: Base64, Height: 4, ID: 1, Name: "chunks", Opacity: 1, Type: TileLayer, Visible: true, Width: 4,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, _ := os.ReadFile(tc.data)
+			var l Layer
+			if err := json.Unmarshal(b, &l); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.expect, l); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLayer_JSON(t *testing.T) {
+	testcases := []struct {
+		name        string
+		enc         Encoding
+		compression string
+		wantErr     error
+	}{
+		{name: "default"},
+		{name: "csv", enc: CSV},
+		{name: "base64", enc: Base64},
+		{name: "zlib", enc: Base64, compression: Zlib},
+		{name: "gzip", enc: Base64, compression: Gzip},
+		{name: "zstd", enc: Base64, compression: Zstd},
+		{name: "csv with zlib", enc: CSV, compression: Zlib, wantErr: ErrUnsupportedCompression},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewTileLayer("ground", 1, 3, 2)
+			l.Data = Data{1, 2, 3, 0, 0, 0x80000001}
+			l.Chunks = []Chunk{{Data: Data{4, 5}, Width: 2, Height: 1}}
+			l.Encoding, l.Compression = tc.enc, tc.compression
+
+			group := NewTileLayer("group", 2, 0, 0)
+			group.Type, group.Layers = Group, []Layer{l}
+
+			b, err := json.Marshal(group)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("json.Marshal() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			var got Layer
+			if err = json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(group, got); diff != "" {
+				t.Errorf("json.Unmarshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLayer_UnmarshalJSON_Error(t *testing.T) {
+	testcases := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{name: "unknown compression", data: `{"encoding":"base64","compression":"lzma","data":"AAAA"}`, wantErr: ErrUnsupportedCompression},
+		{name: "invalid zstd", data: `{"encoding":"base64","compression":"zstd","data":"AQAAAA=="}`, wantErr: ErrInvalidData},
+		{name: "invalid base64", data: `{"encoding":"base64","data":"!!"}`, wantErr: ErrInvalidData},
+		{name: "invalid zlib", data: `{"encoding":"base64","compression":"zlib","data":"AQAAAA=="}`, wantErr: ErrInvalidData},
+		{name: "incomplete gid", data: `{"encoding":"base64","data":"AQAA"}`, wantErr: ErrInvalidData},
+		{name: "short data", data: `{"width":2,"height":2,"data":[1,2,3]}`, wantErr: ErrInvalidData},
+		{name: "long base64", data: `{"width":1,"height":1,"encoding":"base64","data":"AQAAAAIAAAA="}`, wantErr: ErrInvalidData},
+		{name: "short chunk", data: `{"chunks":[{"width":2,"height":1,"data":[1]}]}`, wantErr: ErrInvalidData},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var l Layer
+			if err := json.Unmarshal([]byte(tc.data), &l); !errors.Is(err, tc.wantErr) {
+				t.Errorf("json.Unmarshal() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -62,3 +62,17 @@ func NewMap(w, h int, or Orientation, ro RenderOrder) (m Map) {
 	m.Version = "0.0"
 	return
 }
+
+// SetEncoding sets the encoding and compression of the data of all tile layers, including the ones in groups.
+func (m *Map) SetEncoding(enc Encoding, compression string) {
+	setEncoding(m.Layers, enc, compression)
+}
+
+func setEncoding(layers []Layer, enc Encoding, compression string) {
+	for i := range layers {
+		if layers[i].Type == TileLayer {
+			layers[i].Encoding, layers[i].Compression = enc, compression
+		}
+		setEncoding(layers[i].Layers, enc, compression)
+	}
+}
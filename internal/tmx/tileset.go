@@ -1,5 +1,7 @@
 package tmx
 
+import "encoding/json"
+
 // ObjectAlignment Controls the alignment for tile objects
 type ObjectAlignment string
 
@@ -22,7 +24,7 @@ type TileSet struct {
 	Class            string           `json:"class,omitempty"`            // The class of the TileSet (since 1.9, optional)
 	Columns          int              `json:"columns"`                    // The number of tile columns in the TileSet
 	FileMode         string           `json:"filemode,omitempty"`         // The fill mode to use when rendering tiles from this TileSet (stretch (default) or preserve-aspect-fit) (since 1.9)
-	FirstGID         int              `json:"firstgid"`                   // GID corresponding to the first tile in the set
+	FirstGID         int              `json:"firstgid,omitempty"`         // GID corresponding to the first tile in the set, omitted in the external file
 	Grid             Grid             `json:"grid,omitempty"`             // (optional)
 	Image            string           `json:"image,omitempty"`            // Image used for tiles in this set
 	ImageHeight      int              `json:"imageheight,omitempty"`      // Height of source image in pixels
@@ -50,9 +52,9 @@ type TileSet struct {
 
 // Grid is a struct that represents the grid data.
 type Grid struct {
-	Height      int         `json:"height"`      // Cell height of tile grid
-	Width       int         `json:"width"`       // Orientation or Isometric
-	Orientation Orientation `json:"orientation"` // Cell width of tile grid
+	Height      int         `json:"height" xml:"height,attr"`           // Cell height of tile grid
+	Width       int         `json:"width" xml:"width,attr"`             // Orientation or Isometric
+	Orientation Orientation `json:"orientation" xml:"orientation,attr"` // Cell width of tile grid
 }
 
 // Terrain is a struct that represents the terrain data.
@@ -64,8 +66,8 @@ type Terrain struct {
 
 // TileOffset is a struct that represents the tile offset data.
 type TileOffset struct {
-	X int `json:"x"` // Horizontal offset in pixels
-	Y int `json:"y"` // Vertical offset in pixels (positive is down)
+	X int `json:"x" xml:"x,attr"` // Horizontal offset in pixels
+	Y int `json:"y" xml:"y,attr"` // Vertical offset in pixels (positive is down)
 }
 
 // Tile is a struct that represents the tile data.
@@ -123,8 +125,23 @@ type WangTile struct {
 
 // Frame is a struct that represents the frame data.
 type Frame struct {
-	Duration int `json:"duration"` // Frame duration in milliseconds
-	TileID   int `json:"tileid"`   // Local tile ID representing this frame
+	Duration int `json:"duration" xml:"duration,attr"` // Frame duration in milliseconds
+	TileID   int `json:"tileid" xml:"tileid,attr"`     // Local tile ID representing this frame
+}
+
+// tileSetAlias has the fields of TileSet without its methods.
+type tileSetAlias TileSet
+
+// MarshalJSON encodes ts, only firstgid and source are written for the reference of external tile set.
+func (ts TileSet) MarshalJSON() ([]byte, error) {
+	if ts.Source != "" {
+		return json.Marshal(struct {
+			FirstGID int    `json:"firstgid"`
+			Source   string `json:"source"`
+		}{ts.FirstGID, ts.Source})
+	}
+
+	return json.Marshal(tileSetAlias(ts))
 }
 
 // NewTileSet creates a TileSet.
@@ -42,6 +42,37 @@ func TestDecodeTileSet(t *testing.T) {
 	}
 }
 
+func TestTileSet_MarshalJSON(t *testing.T) {
+	testcases := []struct {
+		name string
+		ts   TileSet
+		want string
+	}{
+		{
+			name: "external",
+			ts:   TileSet{FirstGID: 1, Source: "tiles.tsj", Name: "ignored"},
+			want: `{"firstgid":1,"source":"tiles.tsj"}`,
+		},
+		{
+			name: "external file",
+			ts:   TileSet{Name: "tiles", Type: "tileset"},
+			want: `{"columns":0,"grid":{"height":0,"width":0,"orientation":""},"margin":0,"name":"tiles","spacing":0,"tilecount":0,"tiledversion":"","tileheight":0,"tilewidth":0,"type":"tileset","version":""}`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := json.Marshal(tc.ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, string(b)); diff != "" {
+				t.Errorf("MarshalJSON() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestDecodeTile(t *testing.T) {
 	data, _ := os.ReadFile("testdata/tile.json")
 	expect := Tile{
@@ -0,0 +1,38 @@
+package tmx
+
+import "image"
+
+// World places maps in a larger world, it's saved in a .world file.
+//
+// Ref: https://doc.mapeditor.org/en/stable/manual/worlds/
+type World struct {
+	Maps                 []WorldMap `json:"maps"`                 // Array of WorldMap
+	OnlyShowAdjacentMaps bool       `json:"onlyShowAdjacentMaps"` // Whether only the maps next to the current map are shown
+	Type                 string     `json:"type"`                 // `world`
+}
+
+// WorldMap is a map placed in World, the position and size are in pixels.
+type WorldMap struct {
+	FileName string `json:"fileName"` // Path of the map file, relative to the world file
+	X        int    `json:"x"`        // X position of the map
+	Y        int    `json:"y"`        // Y position of the map
+	Width    int    `json:"width"`    // Width of the map
+	Height   int    `json:"height"`   // Height of the map
+}
+
+// NewWorld creates a World.
+func NewWorld() (w World) {
+	w.Type = "world"
+
+	return
+}
+
+// PixelSize returns the size of m in pixels, isometric maps are diamonds fitting in (Width + Height) halves of tile.
+// Staggered and hexagonal maps are sized like orthogonal maps.
+func (m Map) PixelSize() image.Point {
+	if m.Orientation == Isometric {
+		return image.Pt((m.Width+m.Height)*m.TileWidth/2, (m.Width+m.Height)*m.TileHeight/2)
+	}
+
+	return image.Pt(m.Width*m.TileWidth, m.Height*m.TileHeight)
+}
@@ -0,0 +1,43 @@
+package tmx
+
+import (
+	"encoding/json"
+	"image"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMap_PixelSize(t *testing.T) {
+	testcases := []struct {
+		name string
+		m    Map
+		want image.Point
+	}{
+		{name: "orthogonal", m: Map{Orientation: Orthogonal, Width: 3, Height: 2, TileWidth: 16, TileHeight: 8}, want: image.Pt(48, 16)},
+		{name: "isometric", m: Map{Orientation: Isometric, Width: 3, Height: 2, TileWidth: 64, TileHeight: 47}, want: image.Pt(160, 117)},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.m.PixelSize(); got != tc.want {
+				t.Errorf("PixelSize() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWorld_JSON(t *testing.T) {
+	w := NewWorld()
+	w.Maps = append(w.Maps, WorldMap{FileName: "1000/map.json", X: 10, Y: 20, Width: 30, Height: 40})
+
+	b, err := json.Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"maps":[{"fileName":"1000/map.json","x":10,"y":20,"width":30,"height":40}],"onlyShowAdjacentMaps":false,"type":"world"}`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("json mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,842 @@
+package tmx
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedLayer is returned when the layer type has no XML element.
+	ErrUnsupportedLayer = errors.New("unsupported layer")
+	// ErrUnsupportedValue is returned when the property value can't be written as an XML attribute, such as class.
+	ErrUnsupportedValue = errors.New("unsupported property value")
+)
+
+// layerElements are the XML element names of layer types.
+var layerElements = map[LayerType]string{
+	TileLayer:   "layer",
+	ObjectGroup: "objectgroup",
+	ImageLayer:  "imagelayer",
+	Group:       "group",
+}
+
+// The xml* types are the XML (.tmx and .tsx) forms of the types in this package. Booleans are 0 or 1 in XML, and the
+// ones default to true (such as visible) are written only if they are false.
+
+type xmlMap struct {
+	Version          string         `xml:"version,attr"`
+	TiledVersion     string         `xml:"tiledversion,attr,omitempty"`
+	Class            string         `xml:"class,attr,omitempty"`
+	Orientation      Orientation    `xml:"orientation,attr"`
+	RenderOrder      RenderOrder    `xml:"renderorder,attr,omitempty"`
+	CompressionLevel int            `xml:"compressionlevel,attr,omitempty"`
+	Width            int            `xml:"width,attr"`
+	Height           int            `xml:"height,attr"`
+	TileWidth        int            `xml:"tilewidth,attr"`
+	TileHeight       int            `xml:"tileheight,attr"`
+	HexSideLength    int            `xml:"hexsidelength,attr,omitempty"`
+	StaggerAxis      string         `xml:"staggeraxis,attr,omitempty"`
+	StaggerIndex     string         `xml:"staggerindex,attr,omitempty"`
+	ParallaxOriginX  float64        `xml:"parallaxoriginx,attr,omitempty"`
+	ParallaxOriginY  float64        `xml:"parallaxoriginy,attr,omitempty"`
+	BackgroundColor  string         `xml:"backgroundcolor,attr,omitempty"`
+	NextLayerID      int            `xml:"nextlayerid,attr,omitempty"`
+	NextObjectID     int            `xml:"nextobjectid,attr,omitempty"`
+	Infinite         int            `xml:"infinite,attr"`
+	Properties       *xmlProperties `xml:"properties"`
+	TileSets         []TileSet      `xml:"tileset"`
+	Layers           []Layer        `xml:",any"`
+}
+
+type xmlLayer struct {
+	ID         int            `xml:"id,attr"`
+	Name       string         `xml:"name,attr"`
+	Class      string         `xml:"class,attr,omitempty"`
+	X          int            `xml:"x,attr,omitempty"`
+	Y          int            `xml:"y,attr,omitempty"`
+	Width      int            `xml:"width,attr,omitempty"`
+	Height     int            `xml:"height,attr,omitempty"`
+	Opacity    float64        `xml:"opacity,attr"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	Locked     int            `xml:"locked,attr,omitempty"`
+	TintColor  string         `xml:"tintcolor,attr,omitempty"`
+	OffsetX    float64        `xml:"offsetx,attr,omitempty"`
+	OffsetY    float64        `xml:"offsety,attr,omitempty"`
+	ParallaxX  float64        `xml:"parallaxx,attr,omitempty"`
+	ParallaxY  float64        `xml:"parallaxy,attr,omitempty"`
+	RepeatX    int            `xml:"repeatx,attr,omitempty"`
+	RepeatY    int            `xml:"repeaty,attr,omitempty"`
+	DrawOrder  DrawOrder      `xml:"draworder,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties"`
+	Image      *xmlImage      `xml:"image"`
+	Data       *xmlData       `xml:"data"`
+	Objects    []Object       `xml:"object"`
+	Layers     []Layer        `xml:",any"`
+}
+
+// xmlData is the data of tile layer, it has GIDs in Text, or in Tiles if Encoding is empty, or in Chunks for infinite
+// maps.
+type xmlData struct {
+	Encoding    Encoding   `xml:"encoding,attr,omitempty"`
+	Compression string     `xml:"compression,attr,omitempty"`
+	Chunks      []xmlChunk `xml:"chunk"`
+	Tiles       []xmlTile  `xml:"tile"`
+	Text        string     `xml:",chardata"` // for decoding
+	Raw         string     `xml:",innerxml"` // for encoding, CSV and base64 are safe in XML and kept as is
+}
+
+type xmlChunk struct {
+	X      int       `xml:"x,attr"`
+	Y      int       `xml:"y,attr"`
+	Width  int       `xml:"width,attr"`
+	Height int       `xml:"height,attr"`
+	Tiles  []xmlTile `xml:"tile"`
+	Text   string    `xml:",chardata"` // for decoding
+	Raw    string    `xml:",innerxml"` // for encoding
+}
+
+type xmlTile struct {
+	GID uint `xml:"gid,attr,omitempty"`
+}
+
+type xmlImage struct {
+	Source string `xml:"source,attr"`
+	Trans  string `xml:"trans,attr,omitempty"`
+	Width  int    `xml:"width,attr,omitempty"`
+	Height int    `xml:"height,attr,omitempty"`
+}
+
+type xmlObject struct {
+	ID         int            `xml:"id,attr"`
+	Name       string         `xml:"name,attr,omitempty"`
+	Type       string         `xml:"type,attr,omitempty"`
+	Class      string         `xml:"class,attr,omitempty"` // Type was saved as class in 1.9
+	X          float64        `xml:"x,attr"`
+	Y          float64        `xml:"y,attr"`
+	Width      float64        `xml:"width,attr,omitempty"`
+	Height     float64        `xml:"height,attr,omitempty"`
+	Rotation   float64        `xml:"rotation,attr,omitempty"`
+	GID        int            `xml:"gid,attr,omitempty"`
+	Visible    string         `xml:"visible,attr,omitempty"`
+	Template   string         `xml:"template,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties"`
+	Ellipse    *struct{}      `xml:"ellipse"`
+	Point      *struct{}      `xml:"point"`
+	Polygon    *xmlPoints     `xml:"polygon"`
+	Polyline   *xmlPoints     `xml:"polyline"`
+	Text       *xmlText       `xml:"text"`
+}
+
+// xmlPoints is the points of polygon or polyline, in "x1,y1 x2,y2 ...".
+type xmlPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+type xmlText struct {
+	FontFamily string `xml:"fontfamily,attr,omitempty"`
+	PixelSize  int    `xml:"pixelsize,attr,omitempty"`
+	Wrap       int    `xml:"wrap,attr,omitempty"`
+	Color      string `xml:"color,attr,omitempty"`
+	Bold       int    `xml:"bold,attr,omitempty"`
+	Italic     int    `xml:"italic,attr,omitempty"`
+	Underline  int    `xml:"underline,attr,omitempty"`
+	Strikeout  int    `xml:"strikeout,attr,omitempty"`
+	Kerning    string `xml:"kerning,attr,omitempty"`
+	HAlign     string `xml:"halign,attr,omitempty"`
+	VAlign     string `xml:"valign,attr,omitempty"`
+	Text       string `xml:",chardata"`
+}
+
+type xmlProperties struct {
+	Properties []Property `xml:"property"`
+}
+
+type xmlAnimation struct {
+	Frames []Frame `xml:"frame"`
+}
+
+type xmlProperty struct {
+	Name         string `xml:"name,attr"`
+	Type         string `xml:"type,attr,omitempty"`
+	PropertyType string `xml:"propertytype,attr,omitempty"`
+	Value        string `xml:"value,attr"`
+	Text         string `xml:",chardata"` // multi-line string value
+}
+
+type xmlTileSet struct {
+	FirstGID        int                 `xml:"firstgid,attr,omitempty"`
+	Source          string              `xml:"source,attr,omitempty"`
+	Version         string              `xml:"version,attr,omitempty"`
+	TiledVersion    string              `xml:"tiledversion,attr,omitempty"`
+	Name            string              `xml:"name,attr,omitempty"`
+	Class           string              `xml:"class,attr,omitempty"`
+	TileWidth       int                 `xml:"tilewidth,attr,omitempty"`
+	TileHeight      int                 `xml:"tileheight,attr,omitempty"`
+	Spacing         int                 `xml:"spacing,attr,omitempty"`
+	Margin          int                 `xml:"margin,attr,omitempty"`
+	TileCount       int                 `xml:"tilecount,attr,omitempty"`
+	Columns         int                 `xml:"columns,attr,omitempty"`
+	ObjectAlignment ObjectAlignment     `xml:"objectalignment,attr,omitempty"`
+	TileRenderSize  string              `xml:"tilerendersize,attr,omitempty"`
+	FillMode        string              `xml:"fillmode,attr,omitempty"`
+	TileOffset      *TileOffset         `xml:"tileoffset"`
+	Grid            *Grid               `xml:"grid"`
+	Properties      *xmlProperties      `xml:"properties"`
+	Image           *xmlImage           `xml:"image"`
+	Transformations *xmlTransformations `xml:"transformations"`
+	Tiles           []xmlTileSetTile    `xml:"tile"`
+}
+
+type xmlTransformations struct {
+	HFlip               int `xml:"hflip,attr"`
+	VFlip               int `xml:"vflip,attr"`
+	Rotate              int `xml:"rotate,attr"`
+	PreferUntransformed int `xml:"preferuntransformed,attr"`
+}
+
+type xmlTileSetTile struct {
+	ID          int            `xml:"id,attr"`
+	Type        string         `xml:"type,attr,omitempty"`
+	Probability float64        `xml:"probability,attr,omitempty"`
+	X           int            `xml:"x,attr,omitempty"`
+	Y           int            `xml:"y,attr,omitempty"`
+	Width       int            `xml:"width,attr,omitempty"`
+	Height      int            `xml:"height,attr,omitempty"`
+	Properties  *xmlProperties `xml:"properties"`
+	Image       *xmlImage      `xml:"image"`
+	ObjectGroup *Layer         `xml:"objectgroup"`
+	Animation   *xmlAnimation  `xml:"animation"`
+}
+
+// MarshalXML encodes m as the <map> element of TMX file, the caller writes xml.Header.
+func (m Map) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	return e.EncodeElement(xmlMap{
+		Version:          m.Version,
+		TiledVersion:     m.TiledVersion,
+		Class:            m.Class,
+		Orientation:      m.Orientation,
+		RenderOrder:      m.RenderOrder,
+		CompressionLevel: m.CompressionLevel,
+		Width:            m.Width,
+		Height:           m.Height,
+		TileWidth:        m.TileWidth,
+		TileHeight:       m.TileHeight,
+		HexSideLength:    m.HexSideLength,
+		StaggerAxis:      m.StaggerAxis,
+		StaggerIndex:     m.StaggerIndex,
+		ParallaxOriginX:  m.ParallaxOriginX,
+		ParallaxOriginY:  m.ParallaxOriginY,
+		BackgroundColor:  m.BackgroundColor,
+		NextLayerID:      m.NextLayerID,
+		NextObjectID:     m.NextObjectID,
+		Infinite:         boolAttr(m.Infinite),
+		Properties:       newXMLProperties(m.Properties),
+		TileSets:         m.TileSets,
+		Layers:           m.Layers,
+	}, element("map"))
+}
+
+// UnmarshalXML decodes m from the <map> element of TMX file, unknown elements are skipped.
+func (m *Map) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	var x xmlMap
+	if err = d.DecodeElement(&x, &start); err != nil {
+		return
+	}
+
+	*m = Map{
+		BackgroundColor:  x.BackgroundColor,
+		Class:            x.Class,
+		CompressionLevel: x.CompressionLevel,
+		Height:           x.Height,
+		HexSideLength:    x.HexSideLength,
+		Infinite:         x.Infinite != 0,
+		Layers:           knownLayers(x.Layers),
+		NextLayerID:      x.NextLayerID,
+		NextObjectID:     x.NextObjectID,
+		Orientation:      x.Orientation,
+		ParallaxOriginX:  x.ParallaxOriginX,
+		ParallaxOriginY:  x.ParallaxOriginY,
+		Properties:       x.Properties.list(),
+		RenderOrder:      x.RenderOrder,
+		StaggerAxis:      x.StaggerAxis,
+		StaggerIndex:     x.StaggerIndex,
+		TiledVersion:     x.TiledVersion,
+		TileHeight:       x.TileHeight,
+		TileSets:         x.TileSets,
+		TileWidth:        x.TileWidth,
+		Type:             "map",
+		Version:          x.Version,
+		Width:            x.Width,
+	}
+
+	return
+}
+
+// MarshalXML encodes l as <layer>, <objectgroup>, <imagelayer> or <group> by its type. The data of tile layer is
+// encoded by Encoding (CSV by default) and Compression.
+func (l Layer) MarshalXML(e *xml.Encoder, _ xml.StartElement) (err error) {
+	name, ok := layerElements[l.Type]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedLayer, l.Type)
+	}
+
+	x := xmlLayer{
+		ID:         l.ID,
+		Name:       l.Name,
+		Class:      l.Class,
+		X:          l.X,
+		Y:          l.Y,
+		Width:      l.Width,
+		Height:     l.Height,
+		Opacity:    l.Opacity,
+		Visible:    hiddenAttr(l.Visible),
+		Locked:     boolAttr(l.Locked),
+		TintColor:  l.TintColor,
+		OffsetX:    l.OffsetX,
+		OffsetY:    l.OffsetY,
+		ParallaxX:  l.ParallaxX,
+		ParallaxY:  l.ParallaxY,
+		RepeatX:    boolAttr(l.RepeatX),
+		RepeatY:    boolAttr(l.RepeatY),
+		DrawOrder:  l.DrawOrder,
+		Properties: newXMLProperties(l.Properties),
+		Objects:    l.Objects,
+		Layers:     l.Layers,
+	}
+	if l.Image != "" {
+		x.Image = &xmlImage{Source: l.Image, Trans: strings.TrimPrefix(l.TransParentColor, "#")}
+	}
+	if l.Type == TileLayer {
+		if x.Data, err = l.xmlData(); err != nil {
+			return fmt.Errorf("layer %q: %w", l.Name, err)
+		}
+	}
+
+	return e.EncodeElement(x, element(name))
+}
+
+func (l Layer) xmlData() (x *xmlData, err error) {
+	x = &xmlData{Encoding: l.Encoding, Compression: l.Compression}
+	if x.Encoding == "" {
+		x.Encoding = CSV
+	}
+
+	if len(l.Chunks) == 0 {
+		x.Raw, err = encodeXMLData(l.Data, l.Width, x.Encoding, x.Compression)
+
+		return
+	}
+
+	for _, c := range l.Chunks {
+		xc := xmlChunk{X: c.X, Y: c.Y, Width: c.Width, Height: c.Height}
+		if xc.Raw, err = encodeXMLData(c.Data, c.Width, x.Encoding, x.Compression); err != nil {
+			return
+		}
+		x.Chunks = append(x.Chunks, xc)
+	}
+
+	return
+}
+
+// encodeXMLData encodes data like Tiled does, CSV is written in rows on its own lines.
+func encodeXMLData(data Data, w int, enc Encoding, compression string) (s string, err error) {
+	if s, err = encodeData(data, w, enc, compression); err != nil {
+		return
+	}
+	if enc == CSV {
+		s = "\n" + s + "\n"
+	}
+
+	return
+}
+
+// UnmarshalXML decodes l from <layer>, <objectgroup>, <imagelayer> or <group>, other elements are skipped and l is
+// left with empty Type.
+func (l *Layer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	var t LayerType
+	for k, v := range layerElements {
+		if v == start.Name.Local {
+			t = k
+		}
+	}
+	if t == "" {
+		return d.Skip()
+	}
+
+	x := xmlLayer{Opacity: 1}
+	if err = d.DecodeElement(&x, &start); err != nil {
+		return
+	}
+
+	*l = Layer{
+		Class:      x.Class,
+		DrawOrder:  x.DrawOrder,
+		Height:     x.Height,
+		ID:         x.ID,
+		Layers:     knownLayers(x.Layers),
+		Locked:     x.Locked != 0,
+		Name:       x.Name,
+		Objects:    x.Objects,
+		OffsetX:    x.OffsetX,
+		OffsetY:    x.OffsetY,
+		Opacity:    x.Opacity,
+		ParallaxX:  x.ParallaxX,
+		ParallaxY:  x.ParallaxY,
+		Properties: x.Properties.list(),
+		RepeatX:    x.RepeatX != 0,
+		RepeatY:    x.RepeatY != 0,
+		TintColor:  x.TintColor,
+		Type:       t,
+		Visible:    x.Visible != "0",
+		Width:      x.Width,
+		X:          x.X,
+		Y:          x.Y,
+	}
+	if x.Image != nil {
+		l.Image = x.Image.Source
+		if x.Image.Trans != "" {
+			l.TransParentColor = "#" + x.Image.Trans
+		}
+	}
+	if x.Data != nil {
+		if err = l.setXMLData(*x.Data); err != nil {
+			return fmt.Errorf("layer %q: %w", l.Name, err)
+		}
+	}
+
+	return
+}
+
+func (l *Layer) setXMLData(x xmlData) (err error) {
+	l.Encoding, l.Compression = x.Encoding, x.Compression
+
+	if len(x.Chunks) == 0 {
+		l.Data, err = decodeXMLData(x.Text, x.Tiles, x.Encoding, x.Compression, l.Width, l.Height)
+
+		return
+	}
+
+	for _, xc := range x.Chunks {
+		c := Chunk{X: xc.X, Y: xc.Y, Width: xc.Width, Height: xc.Height}
+		if c.Data, err = decodeXMLData(xc.Text, xc.Tiles, x.Encoding, x.Compression, c.Width, c.Height); err != nil {
+			return
+		}
+		l.Chunks = append(l.Chunks, c)
+	}
+
+	return
+}
+
+// decodeXMLData decodes GIDs of w*h tiles from text, or from <tile> elements if enc is empty.
+func decodeXMLData(text string, tiles []xmlTile, enc Encoding, compression string, w, h int) (data Data, err error) {
+	if enc != "" {
+		return decodeData(text, enc, compression, w, h)
+	}
+
+	data = make(Data, len(tiles))
+	for i, t := range tiles {
+		data[i] = t.GID
+	}
+
+	return data, checkSize(data, w, h)
+}
+
+// knownLayers returns the layers with type, which are not skipped by Layer.UnmarshalXML.
+func knownLayers(layers []Layer) (known []Layer) {
+	for _, l := range layers {
+		if l.Type != "" {
+			known = append(known, l)
+		}
+	}
+
+	return
+}
+
+// MarshalXML encodes o as <object>.
+func (o Object) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	x := xmlObject{
+		ID:         o.ID,
+		Name:       o.Name,
+		Type:       o.Type,
+		X:          o.X,
+		Y:          o.Y,
+		Width:      o.Width,
+		Height:     o.Height,
+		Rotation:   o.Rotation,
+		GID:        o.GID,
+		Visible:    hiddenAttr(o.Visible),
+		Template:   o.Template,
+		Properties: newXMLProperties(o.Properties),
+	}
+	if o.Ellipse {
+		x.Ellipse = &struct{}{}
+	}
+	if o.Point {
+		x.Point = &struct{}{}
+	}
+	if o.Polygon != nil {
+		x.Polygon = &xmlPoints{Points: formatPoints(o.Polygon)}
+	}
+	if o.Polyline != nil {
+		x.Polyline = &xmlPoints{Points: formatPoints(o.Polyline)}
+	}
+	if t := o.Text; t != nil {
+		x.Text = &xmlText{
+			FontFamily: t.FontFamily,
+			PixelSize:  t.PixelSize,
+			Wrap:       boolAttr(t.Wrap),
+			Color:      t.Color,
+			Bold:       boolAttr(t.Bold),
+			Italic:     boolAttr(t.Italic),
+			Underline:  boolAttr(t.Underline),
+			Strikeout:  boolAttr(t.Strikeout),
+			Kerning:    hiddenAttr(t.Kerning),
+			HAlign:     t.HAlign,
+			VAlign:     t.VAlign,
+			Text:       t.Text,
+		}
+	}
+
+	return e.EncodeElement(x, element("object"))
+}
+
+// UnmarshalXML decodes o from <object>.
+func (o *Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	var x xmlObject
+	if err = d.DecodeElement(&x, &start); err != nil {
+		return
+	}
+
+	*o = Object{
+		Ellipse:    x.Ellipse != nil,
+		GID:        x.GID,
+		Height:     x.Height,
+		ID:         x.ID,
+		Name:       x.Name,
+		Point:      x.Point != nil,
+		Properties: x.Properties.list(),
+		Rotation:   x.Rotation,
+		Template:   x.Template,
+		Type:       x.Type,
+		Visible:    x.Visible != "0",
+		Width:      x.Width,
+		X:          x.X,
+		Y:          x.Y,
+	}
+	if o.Type == "" {
+		o.Type = x.Class
+	}
+	if x.Polygon != nil {
+		if o.Polygon, err = parsePoints(x.Polygon.Points); err != nil {
+			return fmt.Errorf("object %d: %w", o.ID, err)
+		}
+	}
+	if x.Polyline != nil {
+		if o.Polyline, err = parsePoints(x.Polyline.Points); err != nil {
+			return fmt.Errorf("object %d: %w", o.ID, err)
+		}
+	}
+	if t := x.Text; t != nil {
+		o.Text = &Text{
+			Bold:       t.Bold != 0,
+			Color:      t.Color,
+			FontFamily: t.FontFamily,
+			HAlign:     t.HAlign,
+			Italic:     t.Italic != 0,
+			Kerning:    t.Kerning != "0",
+			PixelSize:  t.PixelSize,
+			Strikeout:  t.Strikeout != 0,
+			Text:       t.Text,
+			Underline:  t.Underline != 0,
+			VAlign:     t.VAlign,
+			Wrap:       t.Wrap != 0,
+		}
+	}
+
+	return
+}
+
+func formatPoints(points []Point) string {
+	s := make([]string, len(points))
+	for i, p := range points {
+		s[i] = strconv.FormatFloat(p.X, 'f', -1, 64) + "," + strconv.FormatFloat(p.Y, 'f', -1, 64)
+	}
+
+	return strings.Join(s, " ")
+}
+
+func parsePoints(s string) (points []Point, err error) {
+	for _, xy := range strings.Fields(s) {
+		x, y, _ := strings.Cut(xy, ",")
+
+		var p Point
+		if p.X, err = strconv.ParseFloat(x, 64); err != nil {
+			return
+		}
+		if p.Y, err = strconv.ParseFloat(y, 64); err != nil {
+			return
+		}
+		points = append(points, p)
+	}
+
+	return
+}
+
+// MarshalXML encodes p as <property>, the value is written as an attribute, so class values are not supported.
+func (p Property) MarshalXML(e *xml.Encoder, _ xml.StartElement) (err error) {
+	x := xmlProperty{Name: p.Name, Type: p.Type, PropertyType: p.PropertyType}
+	if x.Value, err = formatValue(p.Value); err != nil {
+		return fmt.Errorf("property %q: %w", p.Name, err)
+	}
+
+	return e.EncodeElement(x, element("property"))
+}
+
+// UnmarshalXML decodes p from <property>, the value is decoded by type like encoding/json does: float64 for int,
+// float and object, bool for bool, and string for others.
+func (p *Property) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	var x xmlProperty
+	if err = d.DecodeElement(&x, &start); err != nil {
+		return
+	}
+
+	*p = Property{Name: x.Name, Type: x.Type, PropertyType: x.PropertyType}
+
+	v := x.Value
+	if !hasAttr(start, "value") {
+		v = x.Text
+	}
+
+	switch x.Type {
+	case "int", "float", "object":
+		p.Value, err = strconv.ParseFloat(v, 64)
+	case "bool":
+		p.Value, err = strconv.ParseBool(v)
+	default:
+		p.Value = v
+	}
+	if err != nil {
+		return fmt.Errorf("property %q: %w", p.Name, err)
+	}
+
+	return
+}
+
+func formatValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("%w: %T", ErrUnsupportedValue, v)
+	}
+}
+
+// MarshalXML encodes ts as <tileset>, only firstgid and source are written for the external tile set.
+func (ts TileSet) MarshalXML(e *xml.Encoder, _ xml.StartElement) (err error) {
+	if ts.Source != "" {
+		return e.EncodeElement(xmlTileSet{FirstGID: ts.FirstGID, Source: ts.Source}, element("tileset"))
+	}
+
+	x := xmlTileSet{
+		FirstGID:        ts.FirstGID,
+		Version:         ts.Version,
+		TiledVersion:    ts.TiledVersion,
+		Name:            ts.Name,
+		Class:           ts.Class,
+		TileWidth:       ts.TileWidth,
+		TileHeight:      ts.TileHeight,
+		Spacing:         ts.Spacing,
+		Margin:          ts.Margin,
+		TileCount:       ts.TileCount,
+		Columns:         ts.Columns,
+		ObjectAlignment: ts.ObjectAlignment,
+		TileRenderSize:  ts.TileRenderSize,
+		FillMode:        ts.FileMode,
+		TileOffset:      ts.TileOffset,
+		Properties:      newXMLProperties(ts.Properties),
+	}
+	if ts.Grid != (Grid{}) {
+		x.Grid = &ts.Grid
+	}
+	if ts.Image != "" {
+		x.Image = &xmlImage{
+			Source: ts.Image,
+			Trans:  strings.TrimPrefix(ts.TransparentColor, "#"),
+			Width:  ts.ImageWidth,
+			Height: ts.ImageHeight,
+		}
+	}
+	if t := ts.Transformations; t != nil {
+		x.Transformations = &xmlTransformations{
+			HFlip:               boolAttr(t.HFlip),
+			VFlip:               boolAttr(t.VFlip),
+			Rotate:              boolAttr(t.Rotate),
+			PreferUntransformed: boolAttr(t.PreferUntransformed),
+		}
+	}
+	for _, t := range ts.Tiles {
+		xt := xmlTileSetTile{
+			ID:          t.ID,
+			Type:        t.Type,
+			Probability: t.Probability,
+			X:           t.X,
+			Y:           t.Y,
+			Width:       t.Width,
+			Height:      t.Height,
+			Properties:  newXMLProperties(t.Properties),
+			ObjectGroup: t.ObjectGroup,
+			Animation:   newXMLAnimation(t.Animation),
+		}
+		if t.Image != "" {
+			xt.Image = &xmlImage{Source: t.Image, Width: t.ImageWidth, Height: t.ImageHeight}
+		}
+		x.Tiles = append(x.Tiles, xt)
+	}
+
+	return e.EncodeElement(x, element("tileset"))
+}
+
+// UnmarshalXML decodes ts from <tileset> in TMX file or the root of TSX file.
+func (ts *TileSet) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	var x xmlTileSet
+	if err = d.DecodeElement(&x, &start); err != nil {
+		return
+	}
+
+	*ts = TileSet{
+		Class:           x.Class,
+		Columns:         x.Columns,
+		FileMode:        x.FillMode,
+		FirstGID:        x.FirstGID,
+		Margin:          x.Margin,
+		Name:            x.Name,
+		ObjectAlignment: x.ObjectAlignment,
+		Properties:      x.Properties.list(),
+		Source:          x.Source,
+		Spacing:         x.Spacing,
+		TileCount:       x.TileCount,
+		TiledVersion:    x.TiledVersion,
+		TileHeight:      x.TileHeight,
+		TileOffset:      x.TileOffset,
+		TileRenderSize:  x.TileRenderSize,
+		TileWidth:       x.TileWidth,
+		Version:         x.Version,
+	}
+	if ts.Source == "" {
+		ts.Type = "tileset"
+	}
+	if x.Grid != nil {
+		ts.Grid = *x.Grid
+	}
+	if x.Image != nil {
+		ts.Image, ts.ImageWidth, ts.ImageHeight = x.Image.Source, x.Image.Width, x.Image.Height
+		if x.Image.Trans != "" {
+			ts.TransparentColor = "#" + x.Image.Trans
+		}
+	}
+	if t := x.Transformations; t != nil {
+		ts.Transformations = &Transformations{
+			HFlip:               t.HFlip != 0,
+			VFlip:               t.VFlip != 0,
+			Rotate:              t.Rotate != 0,
+			PreferUntransformed: t.PreferUntransformed != 0,
+		}
+	}
+	for _, xt := range x.Tiles {
+		t := Tile{
+			Animation:   xt.Animation.list(),
+			ID:          xt.ID,
+			X:           xt.X,
+			Y:           xt.Y,
+			Width:       xt.Width,
+			Height:      xt.Height,
+			ObjectGroup: xt.ObjectGroup,
+			Probability: xt.Probability,
+			Properties:  xt.Properties.list(),
+			Type:        xt.Type,
+		}
+		if xt.Image != nil {
+			t.Image, t.ImageWidth, t.ImageHeight = xt.Image.Source, xt.Image.Width, xt.Image.Height
+		}
+		ts.Tiles = append(ts.Tiles, t)
+	}
+
+	return
+}
+
+func newXMLProperties(p []Property) *xmlProperties {
+	if len(p) == 0 {
+		return nil
+	}
+
+	return &xmlProperties{Properties: p}
+}
+
+func (x *xmlProperties) list() []Property {
+	if x == nil {
+		return nil
+	}
+
+	return x.Properties
+}
+
+func newXMLAnimation(frames []Frame) *xmlAnimation {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	return &xmlAnimation{Frames: frames}
+}
+
+func (x *xmlAnimation) list() []Frame {
+	if x == nil {
+		return nil
+	}
+
+	return x.Frames
+}
+
+func element(name string) xml.StartElement {
+	return xml.StartElement{Name: xml.Name{Local: name}}
+}
+
+func hasAttr(start xml.StartElement, name string) bool {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// boolAttr returns 1 for true, it's omitted for false with omitempty.
+func boolAttr(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// hiddenAttr returns "0" for false, it's omitted for true with omitempty, for the attributes default to true.
+func hiddenAttr(b bool) string {
+	if b {
+		return ""
+	}
+
+	return "0"
+}
@@ -0,0 +1,227 @@
+package tmx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestData(t *testing.T) {
+	data := Data{1, 2, 0, 3, 0x80000004, 5}
+
+	testcases := []struct {
+		name        string
+		enc         Encoding
+		compression string
+		wantErr     error
+	}{
+		{name: "default"},
+		{name: "csv", enc: CSV},
+		{name: "base64", enc: Base64},
+		{name: "zlib", enc: Base64, compression: Zlib},
+		{name: "gzip", enc: Base64, compression: Gzip},
+		{name: "zstd", enc: Base64, compression: Zstd},
+		{name: "csv with zlib", enc: CSV, compression: Zlib, wantErr: ErrUnsupportedCompression},
+		{name: "unknown", enc: "xml", wantErr: ErrUnsupportedEncoding},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := encodeData(data, 3, tc.enc, tc.compression)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("encodeData() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			got, err := decodeData(s, tc.enc, tc.compression, 3, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(data, got); diff != "" {
+				t.Errorf("decodeData() mismatch (-want +got):\n%s", diff)
+			}
+
+			// the GIDs of 6 tiles don't fill 3x3 tiles
+			if _, err = decodeData(s, tc.enc, tc.compression, 3, 3); !errors.Is(err, ErrInvalidData) {
+				t.Errorf("decodeData() of 3x3 tiles error = %v, wantErr %v", err, ErrInvalidData)
+			}
+		})
+	}
+}
+
+func TestEncodeData_CSV(t *testing.T) {
+	got, err := encodeData(Data{1, 2, 3, 4, 5, 6}, 3, CSV, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1,2,3,\n4,5,6"; got != want {
+		t.Errorf("encodeData() = %q, want %q", got, want)
+	}
+}
+
+// testMap returns a map with every kind of layer and tile set.
+func testMap() (m Map) {
+	m = NewMap(3, 2, Isometric, LeftUp)
+	m.TileWidth, m.TileHeight = 64, 47
+	m.NextLayerID, m.NextObjectID = 6, 4
+	m.Properties = []Property{
+		{Name: "name", Type: "string", Value: "法蘭城"},
+		{Name: "level", Type: "int", Value: float64(3)},
+		{Name: "ratio", Type: "float", Value: 0.5},
+		{Name: "indoor", Type: "bool", Value: true},
+	}
+
+	ground := NewTileSet("ground", 1, Grid{Orientation: Orthogonal, Width: 1, Height: 1})
+	ground.TileWidth, ground.TileHeight, ground.TileCount = 64, 47, 2
+	ground.TileOffset = &TileOffset{X: -32, Y: 24}
+	ground.Tiles = []Tile{
+		{ID: 0, Image: "ground/1.png", ImageWidth: 64, ImageHeight: 47},
+		{
+			ID: 1, Image: "ground/2.png", ImageWidth: 64, ImageHeight: 47,
+			Properties: []Property{{Name: "mapId", Type: "int", Value: float64(2)}},
+			Animation:  []Frame{{Duration: 100, TileID: 0}, {Duration: 100, TileID: 1}},
+		},
+	}
+	atlas := NewTileSet("atlas", 3, Grid{})
+	atlas.Image, atlas.ImageWidth, atlas.ImageHeight = "atlas.png", 128, 94
+	atlas.TransparentColor = "#ff00ff"
+	atlas.TileWidth, atlas.TileHeight, atlas.TileCount, atlas.Columns = 64, 47, 4, 2
+	atlas.Transformations = &Transformations{HFlip: true}
+	m.TileSets = []TileSet{ground, atlas, {FirstGID: 7, Source: "object.tsx"}}
+
+	ground0 := NewTileLayer("ground", 1, 3, 2)
+	ground0.Data = []uint{1, 2, 0, 3, 4, 0x80000001}
+	ground0.Encoding = CSV
+	ground0.Properties = []Property{{Name: "tileLayerProp", Type: "int", Value: float64(1)}}
+
+	ground1 := NewTileLayer("compressed", 2, 3, 2)
+	ground1.Data = []uint{0, 0, 1, 1, 0, 0}
+	ground1.Encoding, ground1.Compression = Base64, Zlib
+	ground1.Visible, ground1.Opacity = false, 0.5
+
+	objects := NewObjectLayer("object", 3, TopDown)
+	house := NewObject(7, 1, 128, 96)
+	house.X, house.Y, house.Name, house.Type = 32, 64, "house", "house"
+	area := NewObject(0, 2, 0, 0)
+	area.Polygon = []Point{{0, 0}, {10, 0}, {10, -5.5}}
+	label := NewObject(0, 3, 100, 20)
+	label.Visible = false
+	label.Text = &Text{Text: "hello\nworld", Color: "#ff0000", PixelSize: 16, HAlign: "center", Bold: true}
+	objects.Objects = []Object{house, area, label}
+
+	group := NewTileLayer("group", 4, 0, 0)
+	group.Type = Group
+	sky := NewTileLayer("sky", 5, 0, 0)
+	sky.Type, sky.Image, sky.TransParentColor = ImageLayer, "sky.png", "#ff00ff"
+	group.Layers = []Layer{sky}
+
+	m.Layers = []Layer{ground0, ground1, objects, group}
+
+	return
+}
+
+func TestMap_XML(t *testing.T) {
+	m := testMap()
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Map
+	if err = json.Unmarshal(b, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err = xml.MarshalIndent(m, "", " "); err != nil {
+		t.Fatal(err)
+	}
+	var fromXML Map
+	if err = xml.Unmarshal(b, &fromXML); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(fromJSON, fromXML, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("xml.Unmarshal() mismatch with json.Unmarshal() (-json +xml):\n%s", diff)
+	}
+}
+
+func TestDecodeMap_XML(t *testing.T) {
+	data, _ := os.ReadFile("testdata/map.tmx")
+
+	ground := NewTileSet("ground", 1, Grid{Orientation: Orthogonal, Width: 1, Height: 1})
+	ground.TileWidth, ground.TileHeight, ground.TileCount = 64, 47, 1
+	ground.TiledVersion, ground.Version = "", ""
+	ground.Tiles = []Tile{{ID: 0, Image: "ground/1.png", ImageWidth: 64, ImageHeight: 47}}
+
+	layer0 := NewTileLayer("ground", 1, 2, 2)
+	layer0.Data, layer0.Encoding = []uint{1, 0, 0, 1}, CSV
+	layer1 := NewTileLayer("legacy", 2, 2, 2)
+	layer1.Data, layer1.Visible, layer1.Opacity = []uint{1, 0, 0, 1}, false, 0.5
+
+	objects := NewObjectLayer("object", 3, TopDown)
+	house := NewObject(2, 1, 128, 96)
+	house.X, house.Y, house.Type = 32, 64, "house"
+	area := NewObject(0, 2, 0, 0)
+	area.Polygon = []Point{{0, 0}, {10, 0}, {10, -5.5}}
+	objects.Objects = []Object{house, area}
+
+	group := NewTileLayer("group", 4, 0, 0)
+	group.Type = Group
+	sky := NewTileLayer("sky", 5, 0, 0)
+	sky.Type, sky.Image, sky.TransParentColor = ImageLayer, "sky.png", "#ff00ff"
+	group.Layers = []Layer{sky}
+
+	expect := NewMap(2, 2, Isometric, LeftUp)
+	expect.Version, expect.TiledVersion = "1.10", "1.10.2"
+	expect.TileWidth, expect.TileHeight = 64, 47
+	expect.NextLayerID, expect.NextObjectID = 6, 3
+	expect.Properties = []Property{
+		{Name: "note", Value: "first line\nsecond line"},
+		{Name: "level", Type: "int", Value: float64(3)},
+	}
+	expect.TileSets = []TileSet{ground, {FirstGID: 2, Source: "object.tsx"}}
+	expect.Layers = []Layer{layer0, layer1, objects, group}
+
+	var m Map
+	if err := xml.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(expect, m); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLayer_UnmarshalXML_Size(t *testing.T) {
+	testcases := []struct {
+		name string
+		data string
+	}{
+		{name: "csv", data: `<layer id="1" name="a" width="2" height="2"><data encoding="csv">1,2,3</data></layer>`},
+		{name: "tiles", data: `<layer id="1" name="a" width="2" height="1"><data><tile gid="1"/></data></layer>`},
+		{name: "chunk", data: `<layer id="1" name="a" width="2" height="2"><data encoding="csv"><chunk x="0" y="0" width="2" height="1">1,2,3</chunk></data></layer>`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var l Layer
+			if err := xml.Unmarshal([]byte(tc.data), &l); !errors.Is(err, ErrInvalidData) {
+				t.Errorf("xml.Unmarshal() error = %v, wantErr %v", err, ErrInvalidData)
+			}
+		})
+	}
+}
+
+func TestProperty_MarshalXML(t *testing.T) {
+	_, err := xml.Marshal(Property{Name: "class", Type: "class", Value: map[string]any{"a": 1}})
+	if !errors.Is(err, ErrUnsupportedValue) {
+		t.Errorf("xml.Marshal() error = %v, wantErr %v", err, ErrUnsupportedValue)
+	}
+}
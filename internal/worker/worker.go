@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrPanic is the error of a job whose fn panicked.
+var ErrPanic = errors.New("job panicked")
+
+// Run runs fn for every job with n workers, and collects the errors returned by fn. ctx is passed to fn, so long jobs
+// can stop early.
+//
+// A failed job doesn't stop the others, a panic in fn is recovered and returned as the error of its job (ErrPanic). When ctx is done, no more jobs are sent, and Run returns after the running
+// jobs are finished, the caller should check ctx.Err() for cancellation.
+func Run[T any](ctx context.Context, n int, jobs []T, fn func(context.Context, T) error) (errs []error) {
+	n = max(n, 1)
+
+	ch := make(chan T)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for job := range ch {
+				if err := run(ctx, job, fn); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	defer func() {
+		close(ch)
+		wg.Wait()
+	}()
+
+	for _, job := range jobs {
+		select {
+		case <-ctx.Done():
+			return
+		case ch <- job:
+		}
+	}
+
+	return
+}
+
+// run calls fn with job, and turns a panic into an error.
+func run[T any](ctx context.Context, job T, fn func(context.Context, T) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: job=%+v: %v", ErrPanic, job, r)
+		}
+	}()
+
+	return fn(ctx, job)
+}
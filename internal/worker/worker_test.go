@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+var errOdd = errors.New("odd")
+
+func TestRun(t *testing.T) {
+	jobs := make([]int, 1000)
+	for i := range jobs {
+		jobs[i] = i
+	}
+
+	var sum atomic.Int64
+	errs := Run(context.Background(), 8, jobs, func(_ context.Context, i int) error {
+		sum.Add(int64(i))
+		if i%2 == 1 {
+			return errOdd
+		}
+		return nil
+	})
+
+	if sum.Load() != 999*1000/2 {
+		t.Errorf("expected sum: %d, got %d", 999*1000/2, sum.Load())
+	}
+	if len(errs) != 500 {
+		t.Errorf("expected len(errs): %d, got %d", 500, len(errs))
+	}
+	for _, err := range errs {
+		if !errors.Is(err, errOdd) {
+			t.Errorf("expected error: %v, got: %v", errOdd, err)
+		}
+	}
+}
+
+func TestRun_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var cnt atomic.Int64
+	errs := Run(ctx, 4, make([]int, 1000), func(context.Context, int) error {
+		if cnt.Add(1) == 10 {
+			cancel()
+		}
+		return nil
+	})
+
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	if cnt.Load() >= 1000 {
+		t.Errorf("expected jobs stopped after cancel, got %d", cnt.Load())
+	}
+}
+
+func TestRun_Panic(t *testing.T) {
+	var cnt atomic.Int64
+	errs := Run(context.Background(), 4, []int{0, 1, 2, 3, 4, 5}, func(_ context.Context, i int) error {
+		cnt.Add(1)
+		if i == 3 {
+			panic("boom")
+		}
+		return nil
+	})
+
+	if cnt.Load() != 6 {
+		t.Errorf("expected all jobs run: %d, got %d", 6, cnt.Load())
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrPanic) {
+		t.Errorf("expected one error: %v, got %v", ErrPanic, errs)
+	}
+}
@@ -5,11 +5,17 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"image/gif"
 	"io"
-	"unsafe"
+	"math"
+	"sort"
+	"strconv"
+	"xgtool/internal/apng"
+	"xgtool/internal/atlas"
 )
 
 const (
@@ -20,51 +26,117 @@ const (
 	AnimeFrameSize = 10
 )
 
+// ErrGraphicNotFound is returned when the graphic of an anime frame is not in graphic info.
+var ErrGraphicNotFound = errors.New("graphic not found")
+
 // AnimeID is the ID of an anime, from anime info.
 type AnimeID int32
 
 // ActionID is the ID of an action, from anime header
 type ActionID int16
 
-type animeInfo struct {
+// ActionNames maps ActionID to a readable name, it's used to name the dumped animes.
+type ActionNames map[ActionID]string
+
+// DefaultActionNames are the common actions of characters and monsters.
+var DefaultActionNames = ActionNames{
+	0:  "stand",
+	1:  "walk",
+	2:  "prepare",
+	3:  "attack",
+	4:  "hurt",
+	5:  "die",
+	6:  "guard",
+	7:  "item",
+	8:  "sit",
+	9:  "wave",
+	10: "happy",
+	11: "angry",
+	12: "sad",
+	13: "nod",
+	14: "rock",
+	15: "scissors",
+	16: "paper",
+	17: "fish",
+}
+
+// Name returns the name of act, or the number if act is not in n.
+func (n ActionNames) Name(act ActionID) string {
+	if name, ok := n[act]; ok && name != "" {
+		return name
+	}
+
+	return strconv.Itoa(int(act))
+}
+
+// AnimeInfo is a block of anime info file, it points to the anime headers in anime file.
+type AnimeInfo struct {
 	ID     AnimeID
 	Addr   int32
 	ActCnt int16
 	_      int16
 }
 
-type animeHeader struct {
+// AnimeHeader is the header of an anime, followed by FrameCnt frames.
+//
+// Reversed and Sentinel only exist in v3 anime files, they are zero otherwise. The meaning of Reversed is not
+// documented, so it's kept raw and doesn't change how the frames are drawn.
+type AnimeHeader struct {
 	Direct   int16
 	Action   ActionID
-	Duration int32
+	Duration int32 // milliseconds of all frames
 	FrameCnt int32
 	_        int16 // v3 only
 	Reversed int16 // v3 only
-	Sentinel int32 // v3 only
+	Sentinel int32 // v3 only, -1 if the header is extended
 }
 
-type animeFrameData struct {
+// Extended reports whether the header is a v3 header.
+func (h AnimeHeader) Extended() bool {
+	return h.Sentinel == -1
+}
+
+// FrameFlag is the raw flag of an anime frame.
+//
+// The meanings of its bits are not documented, so they are not decoded, Bits lists the set bits for the tools which
+// interpret them.
+type FrameFlag int16
+
+// Bits returns the positions (0 to 15) of the set bits in ascending order, nil if no bit is set.
+func (f FrameFlag) Bits() (bits []int) {
+	for i := 0; i < 16; i++ {
+		if uint16(f)&(1<<i) != 0 {
+			bits = append(bits, i)
+		}
+	}
+
+	return
+}
+
+// AnimeFrameData is a frame in anime file.
+type AnimeFrameData struct {
 	GraphicID int32
 	OffX      int16
 	OffY      int16
-	Flag      int16
+	Flag      FrameFlag
 }
 
-type animeFrame struct {
-	Data    animeFrameData
+// AnimeFrame is a frame with its graphic info, Graphic is nil if the graphic is not found.
+type AnimeFrame struct {
+	Data    AnimeFrameData
 	Graphic *Graphic
 }
 
 // Anime is a collection of frames.
 type Anime struct {
 	Index  AnimeIndex // point to the index of this anime
-	Header animeHeader
-	Frames []animeFrame
+	Header AnimeHeader
+	Frames []AnimeFrame
 }
 
 // AnimeIndex built from anime info, Animes are grouped by ActionID.
 type AnimeIndex struct {
-	Info   animeInfo
+	Info   AnimeInfo
 	Animes map[ActionID][]Anime
 }
 
@@ -87,7 +159,7 @@ func NewAnimeResource(aif io.Reader) (ar AnimeResource, err error) {
 			return
 		}
 
-		var ai animeInfo
+		var ai AnimeInfo
 		if err = binary.Read(buf, binary.LittleEndian, &ai); err != nil {
 			return
 		}
@@ -98,7 +170,9 @@ func NewAnimeResource(aif io.Reader) (ar AnimeResource, err error) {
 	return
 }
 
-// Load loads anime data from anime file.
+// Load loads anime data from anime file, and groups them into aidx.Animes.
+//
+// It seeks af, so loading with the same af isn't safe for concurrent use, see LoadAt.
 func (aidx AnimeIndex) Load(af io.ReadSeeker, gr GraphicResource) (err error) {
 	if _, err = af.Seek(int64(aidx.Info.Addr), io.SeekStart); err != nil {
 		return
@@ -110,40 +184,62 @@ func (aidx AnimeIndex) Load(af io.ReadSeeker, gr GraphicResource) (err error) {
 		return
 	}
 
+	var animes map[ActionID][]Anime
+	if animes, err = aidx.load(af, hsz, gr); err != nil {
+		return
+	}
+
+	for act, a := range animes {
+		aidx.Animes[act] = append(aidx.Animes[act], a...)
+	}
+
+	return
+}
+
+// LoadAt loads anime data from anime file with io.ReaderAt, and returns animes grouped by ActionID.
+//
+// Unlike Load, aidx is not changed and the graphics in gr are not changed, so it's safe for concurrent use.
+func (aidx AnimeIndex) LoadAt(af io.ReaderAt, gr GraphicResource) (animes map[ActionID][]Anime, err error) {
+	hsz := getHeaderSize(io.NewSectionReader(af, int64(aidx.Info.Addr), 20))
+	r := io.NewSectionReader(af, int64(aidx.Info.Addr), math.MaxInt64-int64(aidx.Info.Addr))
+
+	return aidx.load(r, hsz, gr)
+}
+
+// load reads ActCnt animes from r, whose headers are hsz bytes.
+func (aidx AnimeIndex) load(r io.Reader, hsz int, gr GraphicResource) (animes map[ActionID][]Anime, err error) {
+	animes = make(map[ActionID][]Anime)
 	for i := 0; i < int(aidx.Info.ActCnt); i++ {
 		var a Anime
 
 		a.Index = aidx
-		if a.Header, err = a.readHeader(af, hsz); err != nil {
+		if a.Header, err = a.readHeader(r, hsz); err != nil {
 			return
 		}
-		if a.Frames, err = a.readFrames(af, int(a.Header.FrameCnt), gr); err != nil {
+		if a.Frames, err = a.readFrames(r, int(a.Header.FrameCnt), gr); err != nil {
 			return
 		}
 
-		aidx.Animes[a.Header.Action] = append(aidx.Animes[a.Header.Action], a)
+		animes[a.Header.Action] = append(animes[a.Header.Action], a)
 	}
 
 	return
 }
 
-func (a Anime) readHeader(af io.Reader, sz int) (h animeHeader, err error) {
-	buf := bytes.NewBuffer(make([]byte, sz))
-	if _, err = io.ReadFull(af, buf.Bytes()); err != nil {
+func (a Anime) readHeader(af io.Reader, sz int) (h AnimeHeader, err error) {
+	// the buffer is always 20 bytes, the v3 fields are zero if sz is 12
+	buf := bytes.NewBuffer(make([]byte, 20))
+	if _, err = io.ReadFull(af, buf.Bytes()[:sz]); err != nil {
 		return
 	}
 
-	h = *(*animeHeader)(unsafe.Pointer(&buf.Bytes()[0]))
-	if sz == 12 {
-		h.Reversed = 0
-		h.Sentinel = 0
-	}
+	err = binary.Read(buf, binary.LittleEndian, &h)
 
 	return
 }
 
-func (a Anime) readFrames(af io.Reader, cnt int, gr GraphicResource) (f []animeFrame, err error) {
-	f = make([]animeFrame, 0, cnt)
+func (a Anime) readFrames(af io.Reader, cnt int, gr GraphicResource) (f []AnimeFrame, err error) {
+	f = make([]AnimeFrame, 0, cnt)
 
 	buf := bytes.NewBuffer(make([]byte, AnimeFrameSize*cnt))
 	if _, err = io.ReadFull(af, buf.Bytes()); err != nil {
@@ -151,55 +247,297 @@ func (a Anime) readFrames(af io.Reader, cnt int, gr GraphicResource) (f []animeF
 	}
 
 	for i := 0; i < cnt; i++ {
-		var fd animeFrameData
+		var fd AnimeFrameData
 		if err = binary.Read(buf, binary.LittleEndian, &fd); err != nil {
 			return
 		}
 
-		f = append(f, animeFrame{Data: fd, Graphic: gr.IDx.First(fd.GraphicID)})
+		f = append(f, AnimeFrame{Data: fd, Graphic: gr.IDx.First(fd.GraphicID)})
 	}
 
 	return
 }
 
+// Bounds returns the union bounding box of all frames, relative to the anchor of anime.
+//
+// Each frame is placed at the frame offset plus the graphic offset, the anchor (0, 0) is always included.
+func (a Anime) Bounds() (r image.Rectangle, err error) {
+	r = image.Rect(0, 0, 1, 1)
+
+	for _, f := range a.Frames {
+		if f.Graphic == nil {
+			return r, fmt.Errorf("%w: anime=%+v, frame=%+v", ErrGraphicNotFound, a.Index.Info, f.Data)
+		}
+
+		r = r.Union(a.rect(f))
+	}
+
+	return
+}
+
+// rect returns the rectangle of frame relative to the anchor of anime.
+func (a Anime) rect(f AnimeFrame) image.Rectangle {
+	gi := f.Graphic.Info
+	x := int(f.Data.OffX) + int(gi.OffX)
+	y := int(f.Data.OffY) + int(gi.OffY)
+
+	return image.Rect(x, y, x+int(gi.Width), y+int(gi.Height))
+}
+
 // GIF creates a gif from anime frames with given palette
-func (a Anime) GIF(gf io.ReadSeeker, p color.Palette) (img *gif.GIF, err error) {
+//
+// The canvas is the union bounding box of all frames (see Anime.Bounds), and each frame is drawn at its position, so
+// the anchor stays still across frames. The graphics of frames are loaded with io.ReaderAt and not cached, so it's
+// safe for concurrent use.
+func (a Anime) GIF(gf io.ReaderAt, p color.Palette) (img *gif.GIF, err error) {
 	img = new(gif.GIF)
 
-	var w, h int
+	var bounds image.Rectangle
+	if bounds, err = a.Bounds(); err != nil {
+		return nil, err
+	}
+
+	shared := true // all frames are rendered with p
 	for _, f := range a.Frames {
-		if err = f.Graphic.Load(gf); err != nil {
+		var g *Graphic
+		if g, err = f.Graphic.Info.LoadGraphicAt(gf); err != nil {
 			return
 		}
-
-		w = max(w, int(f.Graphic.Header.Width))
-		h = max(h, int(f.Graphic.Header.Height))
+		shared = shared && len(g.PaletteData) == 0
 
 		var i *image.Paletted
-		if i, err = f.Graphic.ImgPaletted(p); err != nil {
+		if i, err = g.imgPaletted(p, image.Rect(0, 0, int(g.Info.Width), int(g.Info.Height)), image.Point{}); err != nil {
 			return
 		}
+		// move the frame to its position in canvas, the pixels are not changed because the stride is the same
+		i.Rect = a.rect(f).Sub(bounds.Min)
 
 		img.Image = append(img.Image, i)
-		img.Delay = append(img.Delay, int(a.Header.Duration)/int(a.Header.FrameCnt)/10)
+		img.Delay = append(img.Delay, a.frameDelay())
 		img.Disposal = append(img.Disposal, gif.DisposalBackground)
 	}
 
 	img.Config = image.Config{
-		Width:  w,
-		Height: h,
+		Width:  bounds.Dx(),
+		Height: bounds.Dy(),
+	}
+	if shared && len(p) > 0 {
+		img.Config.ColorModel = p
+		img.BackgroundIndex = transparentIndex(p)
+	}
+
+	return
+}
+
+// APNG creates an animated PNG from anime frames with given palette
+//
+// Like GIF, the canvas is the union bounding box of all frames. Frames keep full alpha, and the delay of each frame is
+// the exact fraction of Header.Duration (milliseconds) / Header.FrameCnt.
+func (a Anime) APNG(gf io.ReaderAt, p color.Palette) (img *apng.APNG, err error) {
+	img = new(apng.APNG)
+
+	var bounds image.Rectangle
+	if bounds, err = a.Bounds(); err != nil {
+		return nil, err
+	}
+	canvas := bounds.Sub(bounds.Min)
+
+	for _, f := range a.Frames {
+		var g *Graphic
+		if g, err = f.Graphic.Info.LoadGraphicAt(gf); err != nil {
+			return
+		}
+
+		var i *image.RGBA
+		if i, err = g.imgRGBA(p, canvas, a.rect(f).Min.Sub(bounds.Min)); err != nil {
+			return
+		}
+
+		img.Image = append(img.Image, i)
+		img.Delay = append(img.Delay, apng.NewDelay(int(a.Header.Duration), int(a.Header.FrameCnt)))
 	}
 
 	return
 }
 
+// SpriteSheet packs the frames of all loaded animes into one image, and describes them in TexturePacker hash format.
+//
+// Each graphic is packed once even if it's used by many frames. Frames are trimmed from the bounds of their anime, so
+// engines can restore the position, and the pivot is the anchor of anime. name is the file name of the sheet image.
+func (aidx AnimeIndex) SpriteSheet(gf io.ReaderAt, p color.Palette, name string) (img *image.RGBA, sheet atlas.Atlas, err error) {
+	animes := aidx.Sorted()
+
+	// collect the unique graphics
+	var infos []GraphicInfo
+	var sizes []image.Point
+	index := make(map[int32]int) // graphic id => index of infos
+	for _, a := range animes {
+		for _, f := range a.Frames {
+			if f.Graphic == nil {
+				return nil, sheet, fmt.Errorf("%w: anime=%+v, frame=%+v", ErrGraphicNotFound, a.Index.Info, f.Data)
+			}
+			if _, ok := index[f.Graphic.Info.ID]; ok {
+				continue
+			}
+
+			index[f.Graphic.Info.ID] = len(infos)
+			infos = append(infos, f.Graphic.Info)
+			sizes = append(sizes, image.Pt(int(f.Graphic.Info.Width), int(f.Graphic.Info.Height)))
+		}
+	}
+
+	rects, size := atlas.Pack(sizes, 1)
+	img = image.NewRGBA(image.Rectangle{Max: size})
+	for i, info := range infos {
+		var g *Graphic
+		if g, err = info.LoadGraphicAt(gf); err != nil {
+			return
+		}
+
+		var gi *image.RGBA
+		if gi, err = g.ImgRGBA(p); err != nil {
+			return
+		}
+
+		draw.Draw(img, rects[i], gi, image.Point{}, draw.Src)
+	}
+
+	sheet = atlas.NewAtlas(name, size.X, size.Y)
+	for _, a := range animes {
+		var bounds image.Rectangle
+		if bounds, err = a.Bounds(); err != nil {
+			return
+		}
+		pivot := atlas.Point{
+			X: float64(-bounds.Min.X) / float64(bounds.Dx()),
+			Y: float64(-bounds.Min.Y) / float64(bounds.Dy()),
+		}
+
+		anim := fmt.Sprintf("%d-%d", a.Header.Action, a.Header.Direct)
+		for n := 1; sheet.Animations[anim] != nil; n++ {
+			anim = fmt.Sprintf("%d-%d-%d", a.Header.Action, a.Header.Direct, n)
+		}
+
+		for i, f := range a.Frames {
+			r := rects[index[f.Graphic.Info.ID]]
+			src := a.rect(f).Sub(bounds.Min)
+			frame := fmt.Sprintf("%s/%d", anim, i)
+
+			sheet.Frames[frame] = atlas.Frame{
+				Frame:            atlas.Rect{X: r.Min.X, Y: r.Min.Y, W: r.Dx(), H: r.Dy()},
+				Trimmed:          true,
+				SpriteSourceSize: atlas.Rect{X: src.Min.X, Y: src.Min.Y, W: src.Dx(), H: src.Dy()},
+				SourceSize:       atlas.Size{W: bounds.Dx(), H: bounds.Dy()},
+				Pivot:            pivot,
+				Duration:         a.frameDuration(i),
+				Direction:        int(a.Header.Direct),
+				Action:           int(a.Header.Action),
+			}
+			sheet.Animations[anim] = append(sheet.Animations[anim], frame)
+		}
+	}
+
+	return
+}
+
+// AnimeMeta describes the loaded animes of an AnimeIndex, it's designed to be marshaled to JSON.
+type AnimeMeta struct {
+	ID      AnimeID      `json:"id"`
+	Actions []ActionMeta `json:"actions"`
+}
+
+// ActionMeta describes an anime, which is an action in a direction.
+type ActionMeta struct {
+	Action    ActionID    `json:"action"`
+	Direction int16       `json:"direction"`
+	Duration  int32       `json:"duration"` // milliseconds
+	Reversed  int16       `json:"reversed"` // raw, v3 only
+	Frames    []FrameMeta `json:"frames"`
+}
+
+// FrameMeta describes a frame, Flag is the raw flag and Bits are its set bits.
+type FrameMeta struct {
+	GraphicID int32     `json:"graphicId"`
+	OffX      int16     `json:"offX"`
+	OffY      int16     `json:"offY"`
+	Duration  int       `json:"duration"` // milliseconds
+	Flag      FrameFlag `json:"flag"`
+	Bits      []int     `json:"bits,omitempty"`
+}
+
+// Meta returns the metadata of loaded animes, sorted by ActionID.
+func (aidx AnimeIndex) Meta() (m AnimeMeta) {
+	m = AnimeMeta{ID: aidx.Info.ID, Actions: []ActionMeta{}}
+
+	for _, a := range aidx.Sorted() {
+		am := ActionMeta{
+			Action:    a.Header.Action,
+			Direction: a.Header.Direct,
+			Duration:  a.Header.Duration,
+			Reversed:  a.Header.Reversed,
+			Frames:    make([]FrameMeta, 0, len(a.Frames)),
+		}
+		for i, f := range a.Frames {
+			am.Frames = append(am.Frames, FrameMeta{
+				GraphicID: f.Data.GraphicID,
+				OffX:      f.Data.OffX,
+				OffY:      f.Data.OffY,
+				Duration:  a.frameDuration(i),
+				Flag:      f.Data.Flag,
+				Bits:      f.Data.Flag.Bits(),
+			})
+		}
+
+		m.Actions = append(m.Actions, am)
+	}
+
+	return
+}
+
+// Sorted returns the loaded animes sorted by ActionID, the order of animes with the same ActionID is kept.
+func (aidx AnimeIndex) Sorted() (animes []Anime) {
+	acts := make([]ActionID, 0, len(aidx.Animes))
+	for act := range aidx.Animes {
+		acts = append(acts, act)
+	}
+	sort.Slice(acts, func(i, j int) bool { return acts[i] < acts[j] })
+
+	for _, act := range acts {
+		animes = append(animes, aidx.Animes[act]...)
+	}
+
+	return
+}
+
+// frameDuration returns the duration of i-th frame in milliseconds, the sum of all frames is Header.Duration.
+func (a Anime) frameDuration(i int) int {
+	d, n := int(a.Header.Duration), int(a.Header.FrameCnt)
+	if n == 0 {
+		return 0
+	}
+
+	return (i+1)*d/n - i*d/n
+}
+
+// frameDelay returns the delay of each frame in 100ths of a second.
+func (a Anime) frameDelay() int {
+	if a.Header.FrameCnt == 0 {
+		return 0
+	}
+
+	return int(a.Header.Duration) / int(a.Header.FrameCnt) / 10
+}
+
 func getHeaderSize(af io.Reader) (sz int) {
 	buf := bytes.NewBuffer(make([]byte, 20))
 	if _, err := io.ReadFull(af, buf.Bytes()); err != nil {
-		return
+		return 12
 	}
 
-	h := *(*animeHeader)(unsafe.Pointer(&buf.Bytes()[0]))
+	var h AnimeHeader
+	if err := binary.Read(buf, binary.LittleEndian, &h); err != nil {
+		return 12
+	}
 
 	// check if this anime header is extended or not
 	// h.Sentinel will be -1 if it's extended
@@ -1,12 +1,20 @@
 package pkg
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"github.com/google/go-cmp/cmp"
 	"github.com/samber/lo"
 	"golang.org/x/exp/maps"
+	"image"
+	"image/color"
 	"image/gif"
+	"io"
 	"os"
+	"sync"
 	"testing"
+	"xgtool/internal/apng"
 )
 
 func TestNewAnimeResource(t *testing.T) {
@@ -320,3 +328,405 @@ func TestAnime_GIF_InternalPalette(t *testing.T) {
 		})
 	}
 }
+
+func TestAnimeIndex_LoadAt_Concurrent(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}
+	gif, gf := writeGraphics(t, 16, palette)
+	aif, af := writeAnimes(t, 32, 4, 16)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gif))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ar, err := NewAnimeResource(bytes.NewReader(aif))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ar2, gr2 := bytes.NewReader(af), bytes.NewReader(gf)
+	jobs := make(chan AnimeIndex)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for aidx := range jobs {
+				loaded, err := aidx.LoadAt(ar2, gr)
+				if err != nil {
+					t.Error(err)
+					continue
+				}
+				if sum := lo.SumBy(maps.Values(loaded), func(animes []Anime) int { return len(animes) }); sum != int(aidx.Info.ActCnt) {
+					t.Errorf("anime %d: expected sum: %d, got %d", aidx.Info.ID, aidx.Info.ActCnt, sum)
+				}
+
+				for _, animes := range loaded {
+					for _, a := range animes {
+						img, err := a.GIF(gr2, palette)
+						if err != nil {
+							t.Error(err)
+							continue
+						}
+						if len(img.Image) != int(a.Header.FrameCnt) {
+							t.Errorf("expected len(img.Image): %d, got %d", a.Header.FrameCnt, len(img.Image))
+						}
+					}
+				}
+			}
+		}()
+	}
+
+	for _, aidx := range ar {
+		jobs <- aidx
+	}
+	close(jobs)
+	wg.Wait()
+
+	// LoadAt doesn't change the index
+	for id, aidx := range ar {
+		if len(aidx.Animes) != 0 {
+			t.Errorf("anime %d: expected the index not changed", id)
+		}
+	}
+}
+
+// writeAnimes writes n animes with 12 bytes headers into anime info and anime bytes, each anime has acts actions,
+// and the frames refer to graphics 1..gn. Each edit is called on every action before it's written, to change such as
+// its anime ID (Index.Info.ID), frames or header, the header is written in 20 bytes if it's extended.
+func writeAnimes(t *testing.T, n, acts, gn int, edits ...func(a *Anime)) (aif, af []byte) {
+	t.Helper()
+
+	var ib, ab bytes.Buffer
+	for i := 0; i < n; i++ {
+		info := AnimeInfo{ID: AnimeID(100000 + i), Addr: int32(ab.Len()), ActCnt: int16(acts)}
+
+		for act := 0; act < acts; act++ {
+			cnt := act + 2
+			a := Anime{
+				Index:  AnimeIndex{Info: info},
+				Header: AnimeHeader{Direct: int16(act % 8), Action: ActionID(act / 2), Duration: int32(cnt * 100), FrameCnt: int32(cnt)},
+			}
+			for f := 0; f < cnt; f++ {
+				a.Frames = append(a.Frames, AnimeFrame{Data: AnimeFrameData{GraphicID: int32((i+act+f)%gn + 1), OffX: int16(f), OffY: int16(-f)}})
+			}
+			for _, edit := range edits {
+				edit(&a)
+			}
+			info.ID = a.Index.Info.ID
+
+			var h any = a.Header
+			if !a.Header.Extended() {
+				h = struct {
+					Direct   int16
+					Action   ActionID
+					Duration int32
+					FrameCnt int32
+				}{Direct: a.Header.Direct, Action: a.Header.Action, Duration: a.Header.Duration, FrameCnt: a.Header.FrameCnt}
+			}
+			if err := binary.Write(&ab, binary.LittleEndian, h); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, f := range a.Frames {
+				if err := binary.Write(&ab, binary.LittleEndian, f.Data); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+
+		if err := binary.Write(&ib, binary.LittleEndian, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return ib.Bytes(), ab.Bytes()
+}
+
+func TestAnime_GIF_Offsets(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 2, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// graphic 1: 2x2 at (-1, 0), graphic 2: 3x3 at (-2, -1)
+	a := Anime{
+		Header: AnimeHeader{Duration: 200, FrameCnt: 2},
+		Frames: []AnimeFrame{
+			{Data: AnimeFrameData{GraphicID: 1}, Graphic: gr.IDx.First(1)},
+			{Data: AnimeFrameData{GraphicID: 2, OffX: 5, OffY: -3}, Graphic: gr.IDx.First(2)},
+		},
+	}
+
+	bounds, err := a.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds != image.Rect(-1, -4, 6, 2) {
+		t.Errorf("expected bounds: %v, got %v", image.Rect(-1, -4, 6, 2), bounds)
+	}
+
+	img, err := a.GIF(bytes.NewReader(gf), palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = gif.EncodeAll(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Config.Width != 7 || decoded.Config.Height != 6 {
+		t.Errorf("expected canvas: 7x6, got %dx%d", decoded.Config.Width, decoded.Config.Height)
+	}
+	expect := []image.Rectangle{image.Rect(0, 4, 2, 6), image.Rect(4, 0, 7, 3)}
+	for i, r := range expect {
+		if decoded.Image[i].Bounds() != r {
+			t.Errorf("frame %d: expected bounds: %v, got %v", i, r, decoded.Image[i].Bounds())
+		}
+		if decoded.Disposal[i] != gif.DisposalBackground {
+			t.Errorf("frame %d: expected disposal: %d, got %d", i, gif.DisposalBackground, decoded.Disposal[i])
+		}
+		if _, _, _, a := decoded.Image[i].Palette[0].RGBA(); a != 0 {
+			t.Errorf("frame %d: expected transparent color at index 0", i)
+		}
+	}
+	if decoded.Delay[0] != 10 {
+		t.Errorf("expected delay: %d, got %d", 10, decoded.Delay[0])
+	}
+}
+
+func TestAnime_APNG(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 2, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := Anime{
+		Header: AnimeHeader{Duration: 250, FrameCnt: 2},
+		Frames: []AnimeFrame{
+			{Data: AnimeFrameData{GraphicID: 1}, Graphic: gr.IDx.First(1)},
+			{Data: AnimeFrameData{GraphicID: 2, OffX: 5, OffY: -3}, Graphic: gr.IDx.First(2)},
+		},
+	}
+
+	img, err := a.APNG(bytes.NewReader(gf), palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(img.Image) != 2 {
+		t.Fatalf("expected len(img.Image): %d, got %d", 2, len(img.Image))
+	}
+	for i, frame := range img.Image {
+		if frame.Bounds() != image.Rect(0, 0, 7, 6) {
+			t.Errorf("frame %d: expected bounds: %v, got %v", i, image.Rect(0, 0, 7, 6), frame.Bounds())
+		}
+		if img.Delay[i] != (apng.Delay{Num: 1, Den: 8}) {
+			t.Errorf("frame %d: expected delay: 1/8, got %+v", i, img.Delay[i])
+		}
+	}
+
+	// graphic 2 is drawn at (4, 0), its top row is [0, 1, 0]
+	if _, _, _, a := img.Image[1].At(5, 0).RGBA(); a != 0xffff {
+		t.Errorf("expected opaque pixel at (5, 0), got alpha %d", a)
+	}
+	if _, _, _, a := img.Image[1].At(0, 0).RGBA(); a != 0 {
+		t.Errorf("expected transparent pixel at (0, 0), got alpha %d", a)
+	}
+
+	if err = apng.Encode(io.Discard, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnimeIndex_SpriteSheet(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 16, palette)
+	aif, af := writeAnimes(t, 1, 4, 16)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ar, err := NewAnimeResource(bytes.NewReader(aif))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aidx := ar[100000]
+	if err = aidx.Load(bytes.NewReader(af), gr); err != nil {
+		t.Fatal(err)
+	}
+
+	img, sheet, err := aidx.SpriteSheet(bytes.NewReader(gf), palette, "100000.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sheet.Meta.Size.W != img.Bounds().Dx() || sheet.Meta.Size.H != img.Bounds().Dy() {
+		t.Errorf("expected meta size: %v, got %+v", img.Bounds().Size(), sheet.Meta.Size)
+	}
+
+	var cnt, duration int
+	for _, animes := range aidx.Animes {
+		for _, a := range animes {
+			cnt += int(a.Header.FrameCnt)
+			duration += int(a.Header.Duration)
+		}
+	}
+	if len(sheet.Frames) != cnt {
+		t.Errorf("expected len(sheet.Frames): %d, got %d", cnt, len(sheet.Frames))
+	}
+	if len(sheet.Animations) != int(aidx.Info.ActCnt) {
+		t.Errorf("expected len(sheet.Animations): %d, got %d", aidx.Info.ActCnt, len(sheet.Animations))
+	}
+
+	var sum int
+	for name, f := range sheet.Frames {
+		sum += f.Duration
+
+		r := image.Rect(f.Frame.X, f.Frame.Y, f.Frame.X+f.Frame.W, f.Frame.Y+f.Frame.H)
+		if !r.In(img.Bounds()) {
+			t.Errorf("frame %s: %v is out of sheet", name, r)
+		}
+		src := image.Rect(f.SpriteSourceSize.X, f.SpriteSourceSize.Y, f.SpriteSourceSize.X+f.SpriteSourceSize.W, f.SpriteSourceSize.Y+f.SpriteSourceSize.H)
+		if !src.In(image.Rect(0, 0, f.SourceSize.W, f.SourceSize.H)) {
+			t.Errorf("frame %s: %v is out of source %+v", name, src, f.SourceSize)
+		}
+	}
+	if sum != duration {
+		t.Errorf("expected sum of durations: %d, got %d", duration, sum)
+	}
+}
+
+func TestFrameFlag_Bits(t *testing.T) {
+	testcases := []struct {
+		flag FrameFlag
+		bits []int
+	}{
+		{0, nil},
+		{1, []int{0}},
+		{0x6, []int{1, 2}},
+		{0x103, []int{0, 1, 8}},
+		{-1, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("%#x", uint16(tc.flag)), func(t *testing.T) {
+			if diff := cmp.Diff(tc.bits, tc.flag.Bits()); diff != "" {
+				t.Errorf("Bits() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestAnime_Reversed checks the raw Reversed of v3 headers doesn't change how the frames are drawn.
+func TestAnime_Reversed(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 2, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// graphic 1: 2x2 at (-1, 0), every row is [0, 1], it's at (2, 0) with the frame offset
+	a := Anime{
+		Header: AnimeHeader{Duration: 100, FrameCnt: 1, Reversed: 1, Sentinel: -1},
+		Frames: []AnimeFrame{
+			{Data: AnimeFrameData{GraphicID: 1, OffX: 3}, Graphic: gr.IDx.First(1)},
+		},
+	}
+
+	bounds, err := a.Bounds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds != image.Rect(0, 0, 4, 2) {
+		t.Errorf("expected bounds: %v, got %v", image.Rect(0, 0, 4, 2), bounds)
+	}
+
+	img, err := a.APNG(bytes.NewReader(gf), palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := img.Image[0].At(2, 0).RGBA(); a != 0 {
+		t.Errorf("expected transparent pixel at (2, 0), got alpha %d", a)
+	}
+	if _, _, _, a := img.Image[0].At(3, 0).RGBA(); a != 0xffff {
+		t.Errorf("expected opaque pixel at (3, 0), got alpha %d", a)
+	}
+
+	g, err := a.GIF(bytes.NewReader(gf), palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Image[0].Rect != image.Rect(2, 0, 4, 2) {
+		t.Errorf("expected frame bounds: %v, got %v", image.Rect(2, 0, 4, 2), g.Image[0].Rect)
+	}
+	if g.Image[0].ColorIndexAt(2, 0) != 0 || g.Image[0].ColorIndexAt(3, 0) != 1 {
+		t.Errorf("expected row [0, 1], got [%d, %d]", g.Image[0].ColorIndexAt(2, 0), g.Image[0].ColorIndexAt(3, 0))
+	}
+}
+
+func TestAnimeIndex_Meta(t *testing.T) {
+	aidx := AnimeIndex{
+		Info: AnimeInfo{ID: 100000, ActCnt: 2},
+		Animes: map[ActionID][]Anime{
+			5: {{Header: AnimeHeader{Direct: 2, Action: 5, Duration: 300, FrameCnt: 2, Reversed: 1, Sentinel: -1}, Frames: []AnimeFrame{
+				{Data: AnimeFrameData{GraphicID: 7, OffX: 1, OffY: -1}},
+				{Data: AnimeFrameData{GraphicID: 8, Flag: 0x3}},
+			}}},
+			0: {{Header: AnimeHeader{Action: 0, Duration: 100, FrameCnt: 1}, Frames: []AnimeFrame{
+				{Data: AnimeFrameData{GraphicID: 6, Flag: 0x4}},
+			}}},
+		},
+	}
+
+	expected := AnimeMeta{
+		ID: 100000,
+		Actions: []ActionMeta{
+			{Action: 0, Duration: 100, Frames: []FrameMeta{
+				{GraphicID: 6, Duration: 100, Flag: 0x4, Bits: []int{2}},
+			}},
+			{Action: 5, Direction: 2, Duration: 300, Reversed: 1, Frames: []FrameMeta{
+				{GraphicID: 7, OffX: 1, OffY: -1, Duration: 150},
+				{GraphicID: 8, Duration: 150, Flag: 0x3, Bits: []int{0, 1}},
+			}},
+		},
+	}
+
+	if diff := cmp.Diff(expected, aidx.Meta()); diff != "" {
+		t.Errorf("Meta() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestActionNames_Name(t *testing.T) {
+	names := ActionNames{0: "stand", 1: ""}
+
+	testcases := []struct {
+		act      ActionID
+		expected string
+	}{
+		{0, "stand"},
+		{1, "1"},
+		{42, "42"},
+	}
+
+	for _, tc := range testcases {
+		if got := names.Name(tc.act); got != tc.expected {
+			t.Errorf("Name(%d): expected %q, got %q", tc.act, tc.expected, got)
+		}
+	}
+}
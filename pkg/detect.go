@@ -0,0 +1,507 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Family is the client release a resource file belongs to.
+//
+// It's taken from the file name if the name is conventional, otherwise it's inferred from the content: animes with
+// 12 bytes headers are FamilyBase, animes with 20 bytes headers are FamilyJoy if their palette graphics are in their
+// own graphics, or FamilyV3 otherwise (V3, PUK2 and PUK3 can't be told apart).
+type Family string
+
+// The families of resource files, the suffix after the kind in file name, such as GraphicInfoV3_19.bin.
+const (
+	FamilyBase  Family = "1.0/2.0" // GraphicInfo_66.bin
+	FamilyEx    Family = "Ex"      // GraphicInfoEx_5.bin
+	FamilyV3    Family = "V3"      // GraphicInfoV3_19.bin
+	FamilyPUK2  Family = "PUK2"    // GraphicInfo_PUK2_2.bin
+	FamilyPUK3  Family = "PUK3"    // GraphicInfo_PUK3_1.bin
+	FamilyJoy   Family = "Joy"     // GraphicInfo_Joy_125.bin, GraphicInfo_Joy_CH1.bin
+	FamilyJoyEX Family = "Joy_EX"  // GraphicInfo_Joy_EX_152.bin
+)
+
+// PaletteFamily returns the family whose graphics hold the palette graphics of animes in family f.
+//
+// V3, PUK2 and PUK3 animes use the palette graphics in V3 graphics, Joy and later use their own graphics. The others
+// have no palette graphics and return an empty Family.
+func (f Family) PaletteFamily() Family {
+	switch f {
+	case FamilyV3, FamilyPUK2, FamilyPUK3:
+		return FamilyV3
+	case FamilyJoy, FamilyJoyEX:
+		return f
+	default:
+		return ""
+	}
+}
+
+// FileKind is the kind of resource file.
+type FileKind string
+
+// The kinds of resource files.
+const (
+	KindGraphicInfo FileKind = "GraphicInfo"
+	KindGraphic     FileKind = "Graphic"
+	KindAnimeInfo   FileKind = "AnimeInfo"
+	KindAnime       FileKind = "Anime"
+	KindPalette     FileKind = "Palette"
+)
+
+// ErrUnknownFile is returned when neither the content nor the name of file is a known resource file.
+var ErrUnknownFile = errors.New("unknown file")
+
+var (
+	resourceName = regexp.MustCompile(`(?i)^(GraphicInfo|Graphic|AnimeInfo|Anime)(Ex|V3|_PUK2|_PUK3|_Joy_EX|_Joy)?_([0-9A-Za-z]+)\.bin$`)
+	paletteName  = regexp.MustCompile(`(?i)^palet_([0-9A-Za-z]+)\.cgp$`)
+)
+
+// DetectedFile describes a resource file.
+type DetectedFile struct {
+	Path       string   `json:"path"`
+	Kind       FileKind `json:"kind"`
+	Family     Family   `json:"family,omitempty"`
+	Version    string   `json:"version,omitempty"` // from the file name, empty if the name is unconventional
+	Size       int64    `json:"size"`
+	Records    int      `json:"records,omitempty"`    // the number of records in info file
+	HeaderSize int      `json:"headerSize,omitempty"` // the size of anime header, 12 or 20 (v3)
+	Problem    string   `json:"problem,omitempty"`    // why the content doesn't look like the kind
+}
+
+// Combo is a set of files to be used together, such as the arguments of dump-anime.
+//
+// AnimeInfo and Anime are empty for a graphic only combo, GraphicInfo and Graphic are empty if the graphics of the
+// anime family are not found, PaletteGraphicInfo and PaletteGraphic are empty if there are no palette graphics.
+type Combo struct {
+	Family             Family `json:"family"`
+	AnimeInfo          string `json:"animeInfo,omitempty"`
+	Anime              string `json:"anime,omitempty"`
+	HeaderSize         int    `json:"headerSize,omitempty"`
+	GraphicInfo        string `json:"graphicInfo,omitempty"`
+	Graphic            string `json:"graphic,omitempty"`
+	PaletteGraphicInfo string `json:"paletteGraphicInfo,omitempty"`
+	PaletteGraphic     string `json:"paletteGraphic,omitempty"`
+	Palette            string `json:"palette,omitempty"`
+}
+
+// Detection is the result of Detect.
+type Detection struct {
+	Files  []DetectedFile `json:"files"`
+	Combos []Combo        `json:"combos"`
+}
+
+// Detect walks fsys, identifies the resource files by content, and suggests the combos of them.
+//
+// The files are paired by content too: the graphics and animes of info files must be in the data files, the frames
+// of animes must be in the graphics, and the palette graphics must have the anime IDs as MapIDs. The file names are
+// only used to break ties. Files which are unknown by both content and name are skipped.
+func Detect(fsys fs.FS) (d Detection, err error) {
+	dt := detector{fsys: fsys, contents: make(map[string]content)}
+	err = fs.WalkDir(fsys, ".", func(p string, de fs.DirEntry, err error) error {
+		if err != nil || de.IsDir() {
+			return err
+		}
+
+		f, c, err := detectFile(fsys, p)
+		if errors.Is(err, ErrUnknownFile) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		dt.files = append(dt.files, f)
+		dt.contents[p] = c
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	d.Combos = dt.suggest()
+	d.Files = append([]DetectedFile{}, dt.files...)
+
+	return
+}
+
+// DetectFile identifies the resource file p in fsys by content, ErrUnknownFile is returned if it's unknown.
+//
+// When several kinds fit the content, the kind in the file name wins. A file whose content fits no kind is still
+// reported with the kind in its name, and the mismatch is in DetectedFile.Problem.
+func DetectFile(fsys fs.FS, p string) (f DetectedFile, err error) {
+	f, _, err = detectFile(fsys, p)
+
+	return
+}
+
+func detectFile(fsys fs.FS, p string) (f DetectedFile, c content, err error) {
+	f.Path = p
+
+	var r io.ReaderAt
+	var closer io.Closer
+	if r, f.Size, closer, err = open(fsys, p); err != nil {
+		return
+	}
+	defer closer.Close()
+
+	hint, hintFamily, hintVersion, nerr := parseName(path.Base(p))
+	named := nerr == nil
+
+	var chosen *check
+	checks := checkContent(r, f.Size)
+	for i := range checks {
+		if checks[i].problem != "" {
+			continue
+		}
+		if chosen == nil || checks[i].kind == hint {
+			chosen = &checks[i]
+		}
+	}
+
+	switch {
+	case chosen == nil && !named:
+		return f, c, fmt.Errorf("%w: %s", ErrUnknownFile, p)
+	case chosen == nil:
+		// the file is named as a resource file, but broken
+		for i := range checks {
+			if checks[i].kind == hint {
+				chosen = &checks[i]
+			}
+		}
+		f.Problem = chosen.problem
+	case named && chosen.kind != hint:
+		f.Problem = fmt.Sprintf("named as %s, but the content is %s", hint, chosen.kind)
+	}
+
+	f.Kind = chosen.kind
+	f.Records = chosen.records
+	f.HeaderSize = chosen.headerSize
+	c = chosen.content
+	if f.Kind == KindGraphic || f.Kind == KindPalette {
+		f.Records = 0
+	}
+	if named && chosen.kind == hint {
+		f.Family, f.Version = hintFamily, hintVersion
+	}
+
+	if f.Kind == KindAnime && f.Problem == "" {
+		f.Family, f.Problem = animeFamily(f.Family, f.HeaderSize)
+	}
+
+	return
+}
+
+// open opens p in fsys as io.ReaderAt, the file is read into memory if it's not an io.ReaderAt. The caller should
+// close it.
+func open(fsys fs.FS, p string) (r io.ReaderAt, size int64, closer io.Closer, err error) {
+	var file fs.File
+	if file, err = fsys.Open(p); err != nil {
+		return
+	}
+
+	var stat fs.FileInfo
+	if stat, err = file.Stat(); err != nil {
+		_ = file.Close()
+		return
+	}
+	if ra, ok := file.(io.ReaderAt); ok {
+		return ra, stat.Size(), file, nil
+	}
+
+	defer file.Close()
+
+	var data []byte
+	if data, err = io.ReadAll(file); err != nil {
+		return
+	}
+
+	return bytes.NewReader(data), int64(len(data)), io.NopCloser(nil), nil
+}
+
+// animeFamily checks the family in file name against the header size of anime, the family of 12 bytes headers is
+// FamilyBase or FamilyEx, and the others use 20 bytes headers. An empty family is FamilyBase for 12 bytes headers,
+// and left for suggest to infer for 20 bytes headers.
+func animeFamily(named Family, headerSize int) (f Family, problem string) {
+	v3 := headerSize == 20
+	switch {
+	case named == "" && !v3:
+		return FamilyBase, ""
+	case named == "":
+		return "", ""
+	case (named == FamilyBase || named == FamilyEx) == v3:
+		return "", fmt.Sprintf("%d bytes anime headers don't match family %s", headerSize, named)
+	}
+
+	return named, ""
+}
+
+func parseName(name string) (kind FileKind, family Family, version string, err error) {
+	if m := paletteName.FindStringSubmatch(name); m != nil {
+		return KindPalette, "", m[1], nil
+	}
+
+	m := resourceName.FindStringSubmatch(name)
+	if m == nil {
+		return "", "", "", fmt.Errorf("%w: %s", ErrUnknownFile, name)
+	}
+
+	for _, k := range []FileKind{KindGraphicInfo, KindGraphic, KindAnimeInfo, KindAnime} {
+		if strings.EqualFold(m[1], string(k)) {
+			kind = k
+		}
+	}
+
+	switch strings.ToLower(m[2]) {
+	case "":
+		family = FamilyBase
+	case "ex":
+		family = FamilyEx
+	case "v3":
+		family = FamilyV3
+	case "_puk2":
+		family = FamilyPUK2
+	case "_puk3":
+		family = FamilyPUK3
+	case "_joy":
+		family = FamilyJoy
+	case "_joy_ex":
+		family = FamilyJoyEX
+	}
+
+	return kind, family, m[3], nil
+}
+
+// detector pairs the detected files by their content.
+type detector struct {
+	fsys     fs.FS
+	files    []DetectedFile
+	contents map[string]content
+}
+
+// pair is an info file with its data file.
+type pair struct {
+	info, data int // indexes in detector.files
+}
+
+// suggest makes the combos from files, every anime pair is combined with the graphic pair holding its frames, and the
+// graphic pair holding its palette graphics for 20 bytes headers. The families inferred are set to the files.
+func (dt *detector) suggest() (combos []Combo) {
+	graphics := dt.pairs(KindGraphicInfo, KindGraphic)
+	animes := dt.pairs(KindAnimeInfo, KindAnime)
+
+	var palette string
+	for _, f := range dt.files {
+		if f.Kind == KindPalette && f.Problem == "" && (palette == "" || f.Path < palette) {
+			palette = f.Path
+		}
+	}
+
+	combos = []Combo{}
+	used := make(map[int]bool) // graphic pairs used by anime combos
+	for _, a := range animes {
+		info, data := &dt.files[a.info], &dt.files[a.data]
+
+		c := Combo{Family: info.Family, AnimeInfo: info.Path, Anime: data.Path, HeaderSize: data.HeaderSize, Palette: palette}
+		if c.Family == "" {
+			c.Family = data.Family
+		}
+
+		// g or pg is -1 if not found, the combo is kept to show what's missing
+		g := dt.best(graphics, c.Family, info.Version, func(p pair) int {
+			return frameCoverage(dt.contents[data.Path].frames, dt.contents[dt.files[p.info].Path])
+		})
+		pg := -1
+		if data.HeaderSize == 20 {
+			pg = dt.best(graphics, c.Family.PaletteFamily(), info.Version, func(p pair) int {
+				r, _, closer, err := open(dt.fsys, dt.files[p.data].Path)
+				if err != nil {
+					return 0
+				}
+				defer closer.Close()
+
+				return paletteCoverage(dt.contents[info.Path], dt.contents[dt.files[p.info].Path], r)
+			})
+		}
+
+		if c.Family == "" {
+			c.Family = FamilyV3
+			if pg >= 0 && pg == g {
+				c.Family = FamilyJoy
+			}
+		}
+		if g >= 0 {
+			used[g] = true
+			c.GraphicInfo, c.Graphic = dt.files[graphics[g].info].Path, dt.files[graphics[g].data].Path
+			dt.inferFamily(graphics[g], c.Family)
+		}
+		if pg >= 0 {
+			c.PaletteGraphicInfo, c.PaletteGraphic = dt.files[graphics[pg].info].Path, dt.files[graphics[pg].data].Path
+			if pg != g {
+				dt.inferFamily(graphics[pg], c.Family.PaletteFamily())
+			}
+		}
+		dt.inferFamily(a, c.Family)
+
+		combos = append(combos, c)
+	}
+
+	// the graphics without animes, such as map tiles
+	for i, g := range graphics {
+		if used[i] {
+			continue
+		}
+
+		info, data := dt.files[g.info], dt.files[g.data]
+		combos = append(combos, Combo{Family: info.Family, GraphicInfo: info.Path, Graphic: data.Path, Palette: palette})
+	}
+
+	return
+}
+
+// inferFamily sets the family of the files of p, if it isn't known from their names.
+func (dt *detector) inferFamily(p pair, family Family) {
+	for _, i := range []int{p.info, p.data} {
+		if dt.files[i].Family == "" {
+			dt.files[i].Family = family
+		}
+	}
+}
+
+// pairs pairs the info files with the data files holding their records, sorted by family, version and path.
+//
+// When several data files fit an info file, the one with the same family and version in name wins. An info file
+// without fitting data file is paired with the data file of the same name, so its problem is shown in the combo.
+func (dt *detector) pairs(info, data FileKind) (ps []pair) {
+	used := make(map[int]bool)
+	for i, fi := range dt.files {
+		if fi.Kind != info {
+			continue
+		}
+
+		best, bestScore := -1, -1
+		for j, fd := range dt.files {
+			if fd.Kind != data || used[j] || !dt.fit(fi, fd) {
+				continue
+			}
+			if score := nameScore(fi, fd.Family, fd.Version); score > bestScore {
+				best, bestScore = j, score
+			}
+		}
+		if best < 0 {
+			for j, fd := range dt.files {
+				if fd.Kind == data && !used[j] && fi.Family != "" && nameScore(fi, fd.Family, fd.Version) == 3 {
+					best = j
+				}
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		used[best] = true
+		ps = append(ps, pair{info: i, data: best})
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		a, b := dt.files[ps[i].info], dt.files[ps[j].info]
+		if a.Family != b.Family {
+			return a.Family < b.Family
+		}
+		if a.Version != b.Version {
+			return versionLess(a.Version, b.Version)
+		}
+
+		return a.Path < b.Path
+	})
+
+	return
+}
+
+// fit reports whether the records of info file are in data file.
+func (dt *detector) fit(info, data DetectedFile) bool {
+	r, size, closer, err := open(dt.fsys, data.Path)
+	if err != nil {
+		return false
+	}
+	defer closer.Close()
+
+	if info.Kind == KindGraphicInfo {
+		return graphicsFit(dt.contents[info.Path], r, size)
+	}
+
+	return animesFit(dt.contents[info.Path], r, size, data.HeaderSize)
+}
+
+// best returns the index of the graphic pair with the highest coverage, or -1 if none is covered. Ties are broken by
+// the name: the same family and version wins, otherwise the latest version which is also numbered or also named, such
+// as Joy_125 for Joy_91. When no pair is covered, the pair matched by name is returned.
+func (dt *detector) best(graphics []pair, family Family, version string, coverage func(pair) int) (best int) {
+	best = -1
+	bestCov, bestScore := 0, 0
+	for i, g := range graphics {
+		cov := coverage(g)
+		score := nameScore(dt.files[g.info], family, version)
+
+		// graphics are sorted by version, so the later one is the latest on ties
+		if cov > bestCov || cov == bestCov && cov > 0 && score >= bestScore {
+			best, bestCov, bestScore = i, cov, score
+		}
+	}
+	if best >= 0 || family == "" {
+		return
+	}
+
+	for i, g := range graphics {
+		if score := nameScore(dt.files[g.info], family, version); score >= 2 && score >= bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return
+}
+
+// nameScore rates how f matches family and version by name: 3 for the same family and version, 2 for the same family
+// and both versions are numbered or named, 1 for the same family, 0 otherwise.
+func nameScore(f DetectedFile, family Family, version string) int {
+	switch {
+	case f.Family == "" || f.Family != family:
+		return 0
+	case strings.EqualFold(f.Version, version):
+		return 3
+	case numbered(f.Version) == numbered(version):
+		return 2
+	}
+
+	return 1
+}
+
+func numbered(version string) bool {
+	_, err := strconv.Atoi(version)
+
+	return err == nil
+}
+
+// versionLess compares versions by number if both are numbered, otherwise by name, numbered versions come first.
+func versionLess(a, b string) bool {
+	if numbered(a) != numbered(b) {
+		return numbered(a)
+	}
+	if numbered(a) {
+		na, _ := strconv.Atoi(a)
+		nb, _ := strconv.Atoi(b)
+
+		return na < nb
+	}
+
+	return strings.ToLower(a) < strings.ToLower(b)
+}
@@ -0,0 +1,298 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// maxSampledAnimes is the number of animes read from the beginning of anime file, their frames are used to find the
+// graphics of animes.
+const maxSampledAnimes = 64
+
+// maxSampledRecords is the number of records in info file, whose data is checked in the data file.
+const maxSampledRecords = 16
+
+// content is what the content checks read from a file, for pairing files without trusting their names.
+type content struct {
+	graphics []GraphicInfo         // records of GraphicInfo, ID is non-decreasing
+	byMapID  map[int32]GraphicInfo // the first graphic of each non-zero MapID in GraphicInfo
+	animes   []AnimeInfo           // records of AnimeInfo
+	frames   []int32               // graphic IDs of the frames of sampled animes in Anime
+}
+
+// check is the result of checking a file as kind, the file is of kind if problem is empty.
+type check struct {
+	kind       FileKind
+	problem    string
+	records    int
+	headerSize int
+	content    content
+}
+
+// checkContent checks r of size as every kind, in the order of how strict the checks are, so the first kind without
+// problem is the most likely one.
+func checkContent(r io.ReaderAt, size int64) []check {
+	return []check{
+		checkGraphic(r, size),
+		checkGraphicInfo(r, size),
+		checkAnimeInfo(r, size),
+		checkAnime(r, size),
+		checkPalette(size),
+	}
+}
+
+func checkGraphic(r io.ReaderAt, size int64) (c check) {
+	c.kind = KindGraphic
+	if !graphicAt(r, 0) {
+		c.problem = "no graphic header at the beginning"
+	}
+
+	return
+}
+
+// graphicAt reports whether there is a graphic header at addr, whose version is known.
+func graphicAt(r io.ReaderAt, addr int64) bool {
+	var h GraphicHeader
+	if err := binary.Read(io.NewSectionReader(r, addr, 16), binary.LittleEndian, &h); err != nil {
+		return false
+	}
+
+	return h.Valid() && h.Version <= 3
+}
+
+// checkGraphicInfo checks the records are 40 bytes, the graphics have sizes, the IDs are non-decreasing (one ID can
+// have several graphics), and the graphics are stored in order without overlapping.
+func checkGraphicInfo(r io.ReaderAt, size int64) (c check) {
+	c.kind = KindGraphicInfo
+	if c.records, c.problem = records(size, GraphicInfoSize); c.problem != "" {
+		return
+	}
+
+	br := bufio.NewReaderSize(io.NewSectionReader(r, 0, size), GraphicInfoSize*100)
+	c.content.graphics = make([]GraphicInfo, c.records)
+	c.content.byMapID = make(map[int32]GraphicInfo)
+	for i := range c.content.graphics {
+		gi := &c.content.graphics[i]
+		if err := binary.Read(br, binary.LittleEndian, gi); err != nil {
+			c.problem = fmt.Sprintf("record %d: %v", i, err)
+			return
+		}
+
+		switch {
+		case gi.Addr < 0 || gi.Len <= 0:
+			c.problem = fmt.Sprintf("record %d: invalid address %d or length %d", i, gi.Addr, gi.Len)
+		case gi.Width <= 0 || gi.Height <= 0:
+			c.problem = fmt.Sprintf("record %d: invalid size %dx%d", i, gi.Width, gi.Height)
+		case i > 0 && gi.ID < c.content.graphics[i-1].ID:
+			c.problem = fmt.Sprintf("record %d: ID %d is less than the previous %d", i, gi.ID, c.content.graphics[i-1].ID)
+		case i > 0 && int64(gi.Addr) < int64(c.content.graphics[i-1].Addr)+int64(c.content.graphics[i-1].Len):
+			c.problem = fmt.Sprintf("record %d: address %d overlaps the previous graphic", i, gi.Addr)
+		}
+		if c.problem != "" {
+			c.content = content{}
+			return
+		}
+
+		if _, ok := c.content.byMapID[gi.MapID]; !ok && gi.MapID != 0 {
+			c.content.byMapID[gi.MapID] = *gi
+		}
+	}
+
+	return
+}
+
+// checkAnimeInfo checks the records are 12 bytes, each anime has actions, and the animes are stored in order.
+func checkAnimeInfo(r io.ReaderAt, size int64) (c check) {
+	c.kind = KindAnimeInfo
+	if c.records, c.problem = records(size, AnimeInfoSize); c.problem != "" {
+		return
+	}
+
+	br := bufio.NewReaderSize(io.NewSectionReader(r, 0, size), AnimeInfoSize*100)
+	c.content.animes = make([]AnimeInfo, c.records)
+	for i := range c.content.animes {
+		ai := &c.content.animes[i]
+		if err := binary.Read(br, binary.LittleEndian, ai); err != nil {
+			c.problem = fmt.Sprintf("record %d: %v", i, err)
+			return
+		}
+
+		switch {
+		case ai.Addr < 0 || ai.ActCnt <= 0:
+			c.problem = fmt.Sprintf("record %d: invalid address %d or action count %d", i, ai.Addr, ai.ActCnt)
+		case i > 0 && ai.ID < c.content.animes[i-1].ID:
+			c.problem = fmt.Sprintf("record %d: ID %d is less than the previous %d", i, ai.ID, c.content.animes[i-1].ID)
+		case i > 0 && ai.Addr <= c.content.animes[i-1].Addr:
+			c.problem = fmt.Sprintf("record %d: address %d is not after the previous %d", i, ai.Addr, c.content.animes[i-1].Addr)
+		}
+		if c.problem != "" {
+			c.content = content{}
+			return
+		}
+	}
+
+	return
+}
+
+// checkAnime checks there is an anime header at the beginning, the header size is 20 if the Sentinel of v3 header is
+// -1, otherwise 12. The frames of the first animes are sampled.
+func checkAnime(r io.ReaderAt, size int64) (c check) {
+	c.kind = KindAnime
+	c.headerSize = getHeaderSize(io.NewSectionReader(r, 0, 20))
+
+	sr := io.NewSectionReader(r, 0, size)
+	for i := 0; i < maxSampledAnimes; i++ {
+		h, err := readAnimeHeader(sr, c.headerSize, size)
+		if err != nil {
+			if i == 0 {
+				c.problem = fmt.Sprintf("no anime header at the beginning: %v", err)
+			}
+			break
+		}
+
+		frames := make([]AnimeFrameData, h.FrameCnt)
+		if err = binary.Read(sr, binary.LittleEndian, frames); err != nil {
+			break
+		}
+		for _, f := range frames {
+			c.content.frames = append(c.content.frames, f.GraphicID)
+		}
+	}
+
+	return
+}
+
+var errInvalidHeader = errors.New("invalid header")
+
+// readAnimeHeader reads an anime header of hsz bytes from r, and checks its frames are in the file of size.
+func readAnimeHeader(r io.ReadSeeker, hsz int, size int64) (h AnimeHeader, err error) {
+	buf := make([]byte, 20)
+	if _, err = io.ReadFull(r, buf[:hsz]); err != nil {
+		return
+	}
+	if err = binary.Read(bytes.NewReader(buf), binary.LittleEndian, &h); err != nil {
+		return
+	}
+
+	var pos int64
+	if pos, err = r.Seek(0, io.SeekCurrent); err != nil {
+		return
+	}
+
+	switch {
+	case h.Direct < 0 || h.Direct > 7:
+		err = fmt.Errorf("%w: direction %d", errInvalidHeader, h.Direct)
+	case h.Duration < 0:
+		err = fmt.Errorf("%w: duration %d", errInvalidHeader, h.Duration)
+	case h.FrameCnt <= 0 || pos+int64(h.FrameCnt)*AnimeFrameSize > size:
+		err = fmt.Errorf("%w: frame count %d", errInvalidHeader, h.FrameCnt)
+	}
+
+	return
+}
+
+// checkPalette checks the size, a CGP palette has no header.
+func checkPalette(size int64) (c check) {
+	c.kind = KindPalette
+	if size != CGPSize {
+		c.problem = fmt.Sprintf("size %d is not %d", size, CGPSize)
+	}
+
+	return
+}
+
+func records(size int64, recordSize int64) (n int, problem string) {
+	if size == 0 || size%recordSize != 0 {
+		problem = fmt.Sprintf("size %d is not a multiple of %d", size, recordSize)
+	}
+
+	return int(size / recordSize), problem
+}
+
+// sampled returns up to maxSampledRecords indexes in [0, n) evenly, including the first and the last.
+func sampled(n int) (idx []int) {
+	if n <= maxSampledRecords {
+		for i := 0; i < n; i++ {
+			idx = append(idx, i)
+		}
+		return
+	}
+
+	for i := 0; i < maxSampledRecords; i++ {
+		idx = append(idx, i*(n-1)/(maxSampledRecords-1))
+	}
+
+	return
+}
+
+// graphicsFit reports whether the graphics of info are in data, the end of the last graphic is in data, and the
+// sampled graphics start with graphic headers.
+func graphicsFit(info content, data io.ReaderAt, size int64) bool {
+	if len(info.graphics) == 0 {
+		return false
+	}
+
+	last := info.graphics[len(info.graphics)-1]
+	if int64(last.Addr)+int64(last.Len) > size {
+		return false
+	}
+	for _, i := range sampled(len(info.graphics)) {
+		if !graphicAt(data, int64(info.graphics[i].Addr)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// animesFit reports whether the sampled animes of info start with valid headers of hsz bytes in data.
+func animesFit(info content, data io.ReaderAt, size int64, hsz int) bool {
+	if len(info.animes) == 0 {
+		return false
+	}
+
+	for _, i := range sampled(len(info.animes)) {
+		addr := int64(info.animes[i].Addr)
+		if _, err := readAnimeHeader(io.NewSectionReader(data, addr, size-addr), hsz, size-addr); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// frameCoverage returns the number of frames whose graphics are in info.
+func frameCoverage(frames []int32, info content) (n int) {
+	gs := info.graphics
+	for _, id := range frames {
+		i := sort.Search(len(gs), func(i int) bool { return gs[i].ID >= id })
+		if i < len(gs) && gs[i].ID == id {
+			n++
+		}
+	}
+
+	return
+}
+
+// paletteCoverage returns the number of sampled animes of ai, which have a palette graphic in info and data. A palette
+// graphic has the anime ID as MapID, and embeds a palette (version 2 or 3).
+func paletteCoverage(ai content, info content, data io.ReaderAt) (n int) {
+	for _, i := range sampled(len(ai.animes)) {
+		gi, ok := info.byMapID[int32(ai.animes[i].ID)]
+		if !ok {
+			continue
+		}
+
+		var h GraphicHeader
+		if err := binary.Read(io.NewSectionReader(data, int64(gi.Addr), 16), binary.LittleEndian, &h); err == nil && h.Valid() && h.Version >= 2 {
+			n++
+		}
+	}
+
+	return
+}
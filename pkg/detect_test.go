@@ -0,0 +1,284 @@
+package pkg
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseName(t *testing.T) {
+	testcases := []struct {
+		name    string
+		kind    FileKind
+		family  Family
+		version string
+	}{
+		{"GraphicInfo_66.bin", KindGraphicInfo, FamilyBase, "66"},
+		{"Graphic_66.bin", KindGraphic, FamilyBase, "66"},
+		{"AnimeInfoEx_1.Bin", KindAnimeInfo, FamilyEx, "1"},
+		{"AnimeEx_1.Bin", KindAnime, FamilyEx, "1"},
+		{"GraphicInfoV3_19.bin", KindGraphicInfo, FamilyV3, "19"},
+		{"AnimeInfo_PUK2_4.bin", KindAnimeInfo, FamilyPUK2, "4"},
+		{"Graphic_PUK3_1.bin", KindGraphic, FamilyPUK3, "1"},
+		{"Anime_Joy_91.bin", KindAnime, FamilyJoy, "91"},
+		{"AnimeInfo_Joy_CH1.Bin", KindAnimeInfo, FamilyJoy, "CH1"},
+		{"GraphicInfo_Joy_EX_152.bin", KindGraphicInfo, FamilyJoyEX, "152"},
+		{"palet_00.cgp", KindPalette, "", "00"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			kind, family, version, err := parseName(tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kind != tc.kind || family != tc.family || version != tc.version {
+				t.Errorf("expected %s/%s/%s, got %s/%s/%s", tc.kind, tc.family, tc.version, kind, family, version)
+			}
+		})
+	}
+
+	for _, name := range []string{"1091.dat", "Graphic.bin", "GraphicInfo_66.txt"} {
+		if _, _, _, err := parseName(name); !errors.Is(err, ErrUnknownFile) {
+			t.Errorf("%s: expected error: %v, got %v", name, ErrUnknownFile, err)
+		}
+	}
+}
+
+// withIDs edits the graphics of writeGraphics to ids, the graphics with MapID embed a palette like palette graphics.
+func withIDs(ids []int32, mapIDs map[int32]int32) func(g *Graphic) {
+	return func(g *Graphic) {
+		g.Info.ID = ids[g.Info.ID-1]
+		if g.Info.MapID = mapIDs[g.Info.ID]; g.Info.MapID != 0 {
+			g.Header.Version = 3
+			g.PaletteData = color.Palette{color.Black, color.White}
+		}
+	}
+}
+
+// withFrames edits the animes of writeAnimes to IDs from first, whose frames are the graphic IDs, with v3 headers if v3.
+func withFrames(first AnimeID, frames []int32, v3 bool) func(a *Anime) {
+	return func(a *Anime) {
+		a.Index.Info.ID += first - 100000
+		a.Header.FrameCnt, a.Frames = int32(len(frames)), nil
+		for _, g := range frames {
+			a.Frames = append(a.Frames, AnimeFrame{Data: AnimeFrameData{GraphicID: g}})
+		}
+		if v3 {
+			a.Header.Sentinel = -1
+		}
+	}
+}
+
+// detectFixtures are the files of a client install, keyed by conventional names.
+func detectFixtures(t *testing.T) map[string][]byte {
+	// 1.0: graphics 1-5 and animes using them
+	gi, g := writeGraphics(t, 5, color.Palette{color.Black, color.White})
+	ai, a := writeAnimes(t, 2, 1, 5, withFrames(100000, []int32{1, 2}, false))
+
+	// V3: palette graphics of PUK2 animes, the frames of PUK2 animes are not found
+	v3i, v3 := writeGraphics(t, 2, color.Palette{color.Black, color.White}, withIDs([]int32{10, 11}, map[int32]int32{10: 100010, 11: 100011}))
+	puk2i, puk2 := writeAnimes(t, 2, 1, 1, withFrames(100010, []int32{20, 21}, true))
+
+	// Joy: the palette graphics are in its own graphics
+	joyi, joy := writeGraphics(t, 3, color.Palette{color.Black, color.White}, withIDs([]int32{30, 31, 32}, map[int32]int32{32: 100020}))
+	joyai, joya := writeAnimes(t, 1, 1, 1, withFrames(100020, []int32{30, 31}, true))
+
+	return map[string][]byte{
+		"bin/GraphicInfo_66.bin":          gi,
+		"bin/Graphic_66.bin":              g,
+		"bin/AnimeInfo_4.bin":             ai,
+		"bin/Anime_4.bin":                 a,
+		"bin/GraphicInfoV3_19.bin":        v3i,
+		"bin/GraphicV3_19.bin":            v3,
+		"bin/puk2/AnimeInfo_PUK2_4.bin":   puk2i,
+		"bin/puk2/Anime_PUK2_4.bin":       puk2,
+		"bin/Joy/GraphicInfo_Joy_125.bin": make([]byte, GraphicInfoSize+1),
+		"bin/Joy/Graphic_Joy_125.bin":     []byte("XX"),
+		"bin/Joy/GraphicInfo_Joy_CH1.bin": joyi,
+		"bin/Joy/Graphic_Joy_CH1.bin":     joy,
+		"bin/Joy/AnimeInfo_Joy_91.bin":    joyai,
+		"bin/Joy/Anime_Joy_91.bin":        joya,
+		"bin/pal/palet_00.cgp":            make([]byte, CGPSize),
+		"bin/GraphicInfo_99.bin":          make([]byte, CGPSize),
+		"map/1091.dat":                    {0},
+	}
+}
+
+func TestDetect(t *testing.T) {
+	fixtures := detectFixtures(t)
+	fsys := fstest.MapFS{}
+	for name, data := range fixtures {
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+
+	d, err := Detect(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]DetectedFile{}
+	for _, f := range d.Files {
+		f.Size = 0 // checked by records
+		files[f.Path] = f
+	}
+	expected := map[string]DetectedFile{
+		"bin/Anime_4.bin":                 {Path: "bin/Anime_4.bin", Kind: KindAnime, Family: FamilyBase, Version: "4", HeaderSize: 12},
+		"bin/AnimeInfo_4.bin":             {Path: "bin/AnimeInfo_4.bin", Kind: KindAnimeInfo, Family: FamilyBase, Version: "4", Records: 2},
+		"bin/Graphic_66.bin":              {Path: "bin/Graphic_66.bin", Kind: KindGraphic, Family: FamilyBase, Version: "66"},
+		"bin/GraphicInfo_66.bin":          {Path: "bin/GraphicInfo_66.bin", Kind: KindGraphicInfo, Family: FamilyBase, Version: "66", Records: 5},
+		"bin/GraphicV3_19.bin":            {Path: "bin/GraphicV3_19.bin", Kind: KindGraphic, Family: FamilyV3, Version: "19"},
+		"bin/GraphicInfoV3_19.bin":        {Path: "bin/GraphicInfoV3_19.bin", Kind: KindGraphicInfo, Family: FamilyV3, Version: "19", Records: 2},
+		"bin/puk2/Anime_PUK2_4.bin":       {Path: "bin/puk2/Anime_PUK2_4.bin", Kind: KindAnime, Family: FamilyPUK2, Version: "4", HeaderSize: 20},
+		"bin/puk2/AnimeInfo_PUK2_4.bin":   {Path: "bin/puk2/AnimeInfo_PUK2_4.bin", Kind: KindAnimeInfo, Family: FamilyPUK2, Version: "4", Records: 2},
+		"bin/Joy/Graphic_Joy_125.bin":     {Path: "bin/Joy/Graphic_Joy_125.bin", Kind: KindGraphic, Family: FamilyJoy, Version: "125", Problem: "no graphic header at the beginning"},
+		"bin/Joy/GraphicInfo_Joy_125.bin": {Path: "bin/Joy/GraphicInfo_Joy_125.bin", Kind: KindGraphicInfo, Family: FamilyJoy, Version: "125", Records: 1, Problem: "size 41 is not a multiple of 40"},
+		"bin/Joy/Graphic_Joy_CH1.bin":     {Path: "bin/Joy/Graphic_Joy_CH1.bin", Kind: KindGraphic, Family: FamilyJoy, Version: "CH1"},
+		"bin/Joy/GraphicInfo_Joy_CH1.bin": {Path: "bin/Joy/GraphicInfo_Joy_CH1.bin", Kind: KindGraphicInfo, Family: FamilyJoy, Version: "CH1", Records: 3},
+		"bin/Joy/Anime_Joy_91.bin":        {Path: "bin/Joy/Anime_Joy_91.bin", Kind: KindAnime, Family: FamilyJoy, Version: "91", HeaderSize: 20},
+		"bin/Joy/AnimeInfo_Joy_91.bin":    {Path: "bin/Joy/AnimeInfo_Joy_91.bin", Kind: KindAnimeInfo, Family: FamilyJoy, Version: "91", Records: 1},
+		"bin/pal/palet_00.cgp":            {Path: "bin/pal/palet_00.cgp", Kind: KindPalette, Version: "00"},
+		"bin/GraphicInfo_99.bin":          {Path: "bin/GraphicInfo_99.bin", Kind: KindPalette, Problem: "named as GraphicInfo, but the content is Palette"},
+	}
+	if diff := cmp.Diff(expected, files); diff != "" {
+		t.Errorf("Detect() files mismatch (-want +got):\n%s", diff)
+	}
+
+	expectedCombos := []Combo{
+		{
+			Family:      FamilyBase,
+			AnimeInfo:   "bin/AnimeInfo_4.bin",
+			Anime:       "bin/Anime_4.bin",
+			HeaderSize:  12,
+			GraphicInfo: "bin/GraphicInfo_66.bin",
+			Graphic:     "bin/Graphic_66.bin",
+			Palette:     "bin/pal/palet_00.cgp",
+		},
+		{
+			Family:             FamilyJoy,
+			AnimeInfo:          "bin/Joy/AnimeInfo_Joy_91.bin",
+			Anime:              "bin/Joy/Anime_Joy_91.bin",
+			HeaderSize:         20,
+			GraphicInfo:        "bin/Joy/GraphicInfo_Joy_CH1.bin",
+			Graphic:            "bin/Joy/Graphic_Joy_CH1.bin",
+			PaletteGraphicInfo: "bin/Joy/GraphicInfo_Joy_CH1.bin",
+			PaletteGraphic:     "bin/Joy/Graphic_Joy_CH1.bin",
+			Palette:            "bin/pal/palet_00.cgp",
+		},
+		{
+			Family:             FamilyPUK2,
+			AnimeInfo:          "bin/puk2/AnimeInfo_PUK2_4.bin",
+			Anime:              "bin/puk2/Anime_PUK2_4.bin",
+			HeaderSize:         20,
+			PaletteGraphicInfo: "bin/GraphicInfoV3_19.bin",
+			PaletteGraphic:     "bin/GraphicV3_19.bin",
+			Palette:            "bin/pal/palet_00.cgp",
+		},
+		// paired by name to show their problems
+		{Family: FamilyJoy, GraphicInfo: "bin/Joy/GraphicInfo_Joy_125.bin", Graphic: "bin/Joy/Graphic_Joy_125.bin", Palette: "bin/pal/palet_00.cgp"},
+		{Family: FamilyV3, GraphicInfo: "bin/GraphicInfoV3_19.bin", Graphic: "bin/GraphicV3_19.bin", Palette: "bin/pal/palet_00.cgp"},
+	}
+	if diff := cmp.Diff(expectedCombos, d.Combos); diff != "" {
+		t.Errorf("Detect() combos mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDetect_Renamed(t *testing.T) {
+	// the same files with meaningless names, the broken files are unknown without their names
+	renamed := map[string]string{
+		"bin/GraphicInfo_66.bin":          "res/a.dat",
+		"bin/Graphic_66.bin":              "res/b.dat",
+		"bin/AnimeInfo_4.bin":             "res/c.dat",
+		"bin/Anime_4.bin":                 "res/d.dat",
+		"bin/GraphicInfoV3_19.bin":        "res/e.dat",
+		"bin/GraphicV3_19.bin":            "res/f.dat",
+		"bin/puk2/AnimeInfo_PUK2_4.bin":   "res/g.dat",
+		"bin/puk2/Anime_PUK2_4.bin":       "res/h.dat",
+		"bin/Joy/GraphicInfo_Joy_125.bin": "res/i.dat",
+		"bin/Joy/Graphic_Joy_125.bin":     "res/j.dat",
+		"bin/Joy/GraphicInfo_Joy_CH1.bin": "res/k.dat",
+		"bin/Joy/Graphic_Joy_CH1.bin":     "res/l.dat",
+		"bin/Joy/AnimeInfo_Joy_91.bin":    "res/m.dat",
+		"bin/Joy/Anime_Joy_91.bin":        "res/n.dat",
+		"bin/pal/palet_00.cgp":            "res/o.dat",
+		"bin/GraphicInfo_99.bin":          "res/p.dat",
+		"map/1091.dat":                    "res/q.dat",
+	}
+	fsys := fstest.MapFS{}
+	for name, data := range detectFixtures(t) {
+		fsys[renamed[name]] = &fstest.MapFile{Data: data}
+	}
+
+	d, err := Detect(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]DetectedFile{}
+	for _, f := range d.Files {
+		f.Size = 0
+		files[f.Path] = f
+	}
+	expected := map[string]DetectedFile{
+		"res/a.dat": {Path: "res/a.dat", Kind: KindGraphicInfo, Family: FamilyBase, Records: 5},
+		"res/b.dat": {Path: "res/b.dat", Kind: KindGraphic, Family: FamilyBase},
+		"res/c.dat": {Path: "res/c.dat", Kind: KindAnimeInfo, Family: FamilyBase, Records: 2},
+		"res/d.dat": {Path: "res/d.dat", Kind: KindAnime, Family: FamilyBase, HeaderSize: 12},
+		"res/e.dat": {Path: "res/e.dat", Kind: KindGraphicInfo, Family: FamilyV3, Records: 2},
+		"res/f.dat": {Path: "res/f.dat", Kind: KindGraphic, Family: FamilyV3},
+		"res/g.dat": {Path: "res/g.dat", Kind: KindAnimeInfo, Family: FamilyV3, Records: 2},
+		"res/h.dat": {Path: "res/h.dat", Kind: KindAnime, Family: FamilyV3, HeaderSize: 20},
+		"res/k.dat": {Path: "res/k.dat", Kind: KindGraphicInfo, Family: FamilyJoy, Records: 3},
+		"res/l.dat": {Path: "res/l.dat", Kind: KindGraphic, Family: FamilyJoy},
+		"res/m.dat": {Path: "res/m.dat", Kind: KindAnimeInfo, Family: FamilyJoy, Records: 1},
+		"res/n.dat": {Path: "res/n.dat", Kind: KindAnime, Family: FamilyJoy, HeaderSize: 20},
+		"res/o.dat": {Path: "res/o.dat", Kind: KindPalette},
+		"res/p.dat": {Path: "res/p.dat", Kind: KindPalette},
+	}
+	if diff := cmp.Diff(expected, files); diff != "" {
+		t.Errorf("Detect() files mismatch (-want +got):\n%s", diff)
+	}
+
+	expectedCombos := []Combo{
+		{Family: FamilyBase, AnimeInfo: "res/c.dat", Anime: "res/d.dat", HeaderSize: 12, GraphicInfo: "res/a.dat", Graphic: "res/b.dat", Palette: "res/o.dat"},
+		{Family: FamilyV3, AnimeInfo: "res/g.dat", Anime: "res/h.dat", HeaderSize: 20, PaletteGraphicInfo: "res/e.dat", PaletteGraphic: "res/f.dat", Palette: "res/o.dat"},
+		{Family: FamilyJoy, AnimeInfo: "res/m.dat", Anime: "res/n.dat", HeaderSize: 20, GraphicInfo: "res/k.dat", Graphic: "res/l.dat", PaletteGraphicInfo: "res/k.dat", PaletteGraphic: "res/l.dat", Palette: "res/o.dat"},
+		{Family: FamilyV3, GraphicInfo: "res/e.dat", Graphic: "res/f.dat", Palette: "res/o.dat"},
+	}
+	if diff := cmp.Diff(expectedCombos, d.Combos); diff != "" {
+		t.Errorf("Detect() combos mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDetectFile(t *testing.T) {
+	gi, _ := writeGraphics(t, 3, color.Palette{color.Black})
+	_, anime := writeAnimes(t, 1, 1, 3, withFrames(100000, []int32{1}, true))
+
+	testcases := []struct {
+		name string
+		data []byte
+		want DetectedFile
+		err  error
+	}{
+		{name: "x.bin", data: gi, want: DetectedFile{Path: "x.bin", Kind: KindGraphicInfo, Size: 120, Records: 3}},
+		{name: "Anime_2.bin", data: anime, want: DetectedFile{Path: "Anime_2.bin", Kind: KindAnime, Version: "2", Size: 30, HeaderSize: 20, Problem: "20 bytes anime headers don't match family 1.0/2.0"}},
+		{name: "AnimeV3_8.bin", data: anime, want: DetectedFile{Path: "AnimeV3_8.bin", Kind: KindAnime, Family: FamilyV3, Version: "8", Size: 30, HeaderSize: 20}},
+		{name: "GraphicInfo_1.bin", data: anime, want: DetectedFile{Path: "GraphicInfo_1.bin", Kind: KindAnime, Size: 30, HeaderSize: 20, Problem: "named as GraphicInfo, but the content is Anime"}},
+		{name: "zero.bin", data: make([]byte, GraphicInfoSize), err: ErrUnknownFile},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DetectFile(fstest.MapFS{tc.name: {Data: tc.data}}, tc.name)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("DetectFile() error = %v, want %v", err, tc.err)
+			}
+			if diff := cmp.Diff(tc.want, got); err == nil && diff != "" {
+				t.Errorf("DetectFile() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -64,7 +64,7 @@ type Graphic struct {
 	Info        GraphicInfo // Pointer of GraphicInfo, for reverse searching.
 	Header      GraphicHeader
 	GraphicData []byte        // The decoded (if needed) data from RawData
-	PaletteData color.Palette // When Version < 2, set palette data from palette file; otherwise, set palette data from graphic file.
+	PaletteData color.Palette // When Version >= 2, palette data from graphic file; otherwise, empty and the palette file should be used.
 }
 
 // GraphicIndex is a map of Graphic, key is the ID of the graphic.
@@ -94,6 +94,22 @@ func (idx GraphicIndex) Load(id int32, gf io.ReadSeeker) (err error) {
 	return
 }
 
+// LoadAt loads graphic data for specific id with io.ReaderAt, and returns new Graphic values.
+//
+// Unlike Load, the graphics in index are not changed, so it's safe for concurrent use.
+func (idx GraphicIndex) LoadAt(id int32, gf io.ReaderAt) (gs []*Graphic, err error) {
+	for _, g := range idx.Find(id) {
+		var lg *Graphic
+		if lg, err = g.Info.LoadGraphicAt(gf); err != nil {
+			return
+		}
+
+		gs = append(gs, lg)
+	}
+
+	return
+}
+
 // GraphicResource is a map of []*Graphic, key is the ID or MapID of the graphic.
 type GraphicResource struct {
 	IDx GraphicIndex // Index by GraphicInfo.ID
@@ -144,6 +160,20 @@ func (gi GraphicInfo) LoadGraphic(gf io.ReadSeeker) (g *Graphic, err error) {
 	return
 }
 
+// LoadGraphicAt loads graphic data from graphic file with io.ReaderAt.
+//
+// It doesn't use the shared offset of gf, so it's safe for concurrent use.
+func (gi GraphicInfo) LoadGraphicAt(gf io.ReaderAt) (g *Graphic, err error) {
+	g = new(Graphic)
+	g.Info = gi
+
+	if err = g.read(io.NewSectionReader(gf, int64(gi.Addr), int64(gi.Len))); err != nil {
+		return
+	}
+
+	return
+}
+
 // Load reads from graphic file, and decode if needed
 func (g *Graphic) Load(f io.ReadSeeker) (err error) {
 	// If GraphicData is not empty, it's already loaded.
@@ -155,8 +185,13 @@ func (g *Graphic) Load(f io.ReadSeeker) (err error) {
 		return
 	}
 
+	return g.read(f)
+}
+
+// read reads the graphic from current position of r, and decode if needed
+func (g *Graphic) read(r io.Reader) (err error) {
 	buf := bytes.NewBuffer(make([]byte, g.Info.Len))
-	if _, err = io.ReadFull(f, buf.Bytes()); err != nil {
+	if _, err = io.ReadFull(r, buf.Bytes()); err != nil {
 		return
 	}
 
@@ -196,23 +231,50 @@ func (g *Graphic) decode(raw []byte) (decoded []byte, err error) {
 	return
 }
 
+// palette returns the embedded palette if exists, otherwise returns p.
+//
+// The graphic is not changed, so rendering the same graphic is safe for concurrent use.
+func (g *Graphic) palette(p color.Palette) (color.Palette, error) {
+	if len(g.PaletteData) > 0 {
+		return g.PaletteData, nil
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: info=%+v, header=%+v", ErrEmptyPalette, g.Info, g.Header)
+	}
+
+	return p, nil
+}
+
 // ImgRGBA convert graphic data to image.RGBA
+//
+// The graphic data is stored bottom-up, the first row of data is the last row of image.
 func (g *Graphic) ImgRGBA(p color.Palette) (img *image.RGBA, err error) {
-	if len(g.PaletteData) == 0 && len(p) == 0 {
-		return nil, fmt.Errorf("%w: info=%+v, header=%+v", ErrEmptyPalette, g.Info, g.Header)
-	} else if len(g.PaletteData) == 0 {
-		g.PaletteData = p
+	return g.imgRGBA(p, image.Rect(0, 0, int(g.Info.Width), int(g.Info.Height)), image.Point{})
+}
+
+// ImgRGBAAnchored is like ImgRGBA, but draws the graphic on the canvas from GraphicInfo.Anchor, the anchor in image is returned.
+func (g *Graphic) ImgRGBAAnchored(p color.Palette) (img *image.RGBA, anchor image.Point, err error) {
+	canvas, anchor := g.Info.Anchor()
+	img, err = g.imgRGBA(p, canvas, anchor.Add(image.Pt(int(g.Info.OffX), int(g.Info.OffY))))
+
+	return
+}
+
+// imgRGBA draws the graphic on canvas with its top-left corner at at.
+func (g *Graphic) imgRGBA(p color.Palette, canvas image.Rectangle, at image.Point) (img *image.RGBA, err error) {
+	if p, err = g.palette(p); err != nil {
+		return
 	}
 
 	w := int(g.Info.Width)
 	h := int(g.Info.Height)
-	img = image.NewRGBA(image.Rect(0, 0, w, h))
+	img = image.NewRGBA(canvas)
 
 	for i, pix := range g.GraphicData {
-		if int(pix) >= len(g.PaletteData) {
-			return nil, fmt.Errorf("%w: info=%+v, header=%+v, g.GraphicData[i]=%d, len(g.PaletteData)=%d", ErrRenderFailed, g.Info, g.Header, pix, len(g.PaletteData))
+		if int(pix) >= len(p) {
+			return nil, fmt.Errorf("%w: info=%+v, header=%+v, g.GraphicData[i]=%d, len(p)=%d", ErrRenderFailed, g.Info, g.Header, pix, len(p))
 		}
-		img.Set(i%w, h-i/w, g.PaletteData[pix])
+		img.Set(at.X+i%w, at.Y+h-1-i/w, p[pix])
 	}
 
 	return
@@ -220,29 +282,83 @@ func (g *Graphic) ImgRGBA(p color.Palette) (img *image.RGBA, err error) {
 
 // ImgPaletted convert graphic data to image.Paletted
 func (g *Graphic) ImgPaletted(p color.Palette) (img *image.Paletted, err error) {
-	if len(g.PaletteData) == 0 && len(p) == 0 {
-		return nil, fmt.Errorf("%w: info=%+v, header=%+v", ErrEmptyPalette, g.Info, g.Header)
-	} else if len(g.PaletteData) == 0 {
-		g.PaletteData = p
+	return g.imgPaletted(p, image.Rect(0, 0, int(g.Info.Width), int(g.Info.Height)), image.Point{})
+}
+
+// ImgPalettedAnchored is like ImgPaletted, but draws the graphic on the canvas from GraphicInfo.Anchor, the anchor in image is returned.
+func (g *Graphic) ImgPalettedAnchored(p color.Palette) (img *image.Paletted, anchor image.Point, err error) {
+	canvas, anchor := g.Info.Anchor()
+	img, err = g.imgPaletted(p, canvas, anchor.Add(image.Pt(int(g.Info.OffX), int(g.Info.OffY))))
+
+	return
+}
+
+// imgPaletted is like imgRGBA, but creates an image.Paletted, pixels outside canvas are dropped.
+func (g *Graphic) imgPaletted(p color.Palette, canvas image.Rectangle, at image.Point) (img *image.Paletted, err error) {
+	if p, err = g.palette(p); err != nil {
+		return
 	}
 
 	w := int(g.Info.Width)
 	h := int(g.Info.Height)
-	r := image.Rect(0, 0, w, h)
-	img = image.NewPaletted(r, g.PaletteData)
+	img = image.NewPaletted(canvas, p)
+	img.Pix = bytes.Repeat([]byte{transparentIndex(p)}, len(img.Pix))
 
 	for i, pix := range g.GraphicData {
 		// The code is based on image.Paletted.Set() from go standard library.
 		// The implementation is very slow because it calls p.Palette.Index(c) for each pixel, but it's not necessary.
 		//
 		// Ref: https://cs.opensource.google/go/go/+/refs/tags/go1.21.4:src/image/image.go;l=1188
-		if !(image.Point{X: i % w, Y: h - i/w}.In(r)) {
+		pt := image.Point{X: at.X + i%w, Y: at.Y + h - 1 - i/w}
+		if !pt.In(canvas) {
 			continue
 		}
-		j := img.PixOffset(i%w, h-i/w)
+		j := img.PixOffset(pt.X, pt.Y)
 
 		img.Pix[j] = pix
 	}
 
 	return
 }
+
+// Anchor returns the canvas containing both the graphic and its anchor, and the position of anchor in the canvas.
+//
+// The anchor is the registration point of graphic in the client, the graphic is drawn at (OffX, OffY) relative to it.
+func (gi GraphicInfo) Anchor() (canvas image.Rectangle, anchor image.Point) {
+	r := image.Rect(int(gi.OffX), int(gi.OffY), int(gi.OffX+gi.Width), int(gi.OffY+gi.Height))
+	r = r.Union(image.Rect(0, 0, 1, 1))
+
+	return r.Sub(r.Min), image.Point{}.Sub(r.Min)
+}
+
+// NewGraphicFromImage converts an image to Graphic with the given header version, each pixel is mapped to the nearest color of p.
+//
+// Pixels with alpha < 50% are mapped to the first transparent color of p. The rows are stored bottom-up, same as ImgPaletted,
+// and p is embedded as Graphic.PaletteData when version >= 2.
+func NewGraphicFromImage(img image.Image, p color.Palette, version byte) (g *Graphic, err error) {
+	if len(p) == 0 {
+		return nil, ErrEmptyPalette
+	}
+	if version > 3 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidVersion, version)
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	g = new(Graphic)
+	g.Info.Width = int32(w)
+	g.Info.Height = int32(h)
+	g.Header.Version = version
+	g.GraphicData = make([]byte, w*h)
+	if version >= 2 {
+		g.PaletteData = p
+	}
+
+	m := newPaletteMapper(p)
+	for i := range g.GraphicData {
+		g.GraphicData[i] = m.index(img.At(b.Min.X+i%w, b.Min.Y+h-1-i/w))
+	}
+
+	return
+}
@@ -3,8 +3,11 @@ package pkg
 import (
 	"bytes"
 	"encoding/binary"
+	"image"
+	"image/color"
 	"io"
 	"os"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -381,3 +384,199 @@ func readGraphicInfo(f *os.File) (gi GraphicInfo, err error) {
 
 	return
 }
+
+func TestNewGraphicFromImage(t *testing.T) {
+	palette := color.Palette{
+		color.Transparent,
+		color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff},
+		color.RGBA{R: 0xf0, G: 0xe0, B: 0xd0, A: 0xff},
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, color.RGBA{R: 0x11, G: 0x21, B: 0x31, A: 0xff}) // nearest to palette[1]
+	src.Set(1, 0, palette[2])
+	src.Set(2, 1, palette[1])
+
+	for version := byte(0); version <= 3; version++ {
+		g, err := NewGraphicFromImage(src, palette, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// bottom-up: the first row of data is the last row of image
+		if diff := cmp.Diff([]byte{0, 0, 1, 1, 2, 0}, g.GraphicData); diff != "" {
+			t.Errorf("version %d: graphic data mismatch (-want +got):\n%s", version, diff)
+		}
+		if (version >= 2) != (len(g.PaletteData) > 0) {
+			t.Errorf("version %d: unexpected len(g.PaletteData): %d", version, len(g.PaletteData))
+		}
+
+		img, err := g.ImgPaletted(palette)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		back, err := NewGraphicFromImage(img, palette, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(g.GraphicData, back.GraphicData); diff != "" {
+			t.Errorf("version %d: round-trip mismatch (-want +got):\n%s", version, diff)
+		}
+	}
+}
+
+func TestGraphicIndex_LoadAt_Concurrent(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}
+	gif, gf := writeGraphics(t, 64, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gif))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(gf)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for id := range gr.IDx {
+				gs, err := gr.IDx.LoadAt(id, r)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(gs) != 1 || len(gs[0].GraphicData) != int(gs[0].Info.Width*gs[0].Info.Height) {
+					t.Errorf("graphic %d: unexpected graphics: %+v", id, gs)
+					return
+				}
+				if _, err = gs[0].ImgRGBA(palette); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for id, gs := range gr.IDx {
+		if len(gs[0].GraphicData) != 0 {
+			t.Errorf("graphic %d: expected the index not changed", id)
+		}
+	}
+}
+
+// writeGraphics writes n graphics (ID = MapID = 1..n) with version 1 into graphic info and graphic bytes. Each edit is
+// called on every graphic before it's written, to change such as its ID, MapID, version or embedded palette.
+func writeGraphics(t *testing.T, n int, p color.Palette, edits ...func(g *Graphic)) (gif, gf []byte) {
+	t.Helper()
+
+	var ib, gb bytes.Buffer
+	w := NewGraphicWriter(&ib, &gb)
+
+	for i := 1; i <= n; i++ {
+		g := &Graphic{
+			Info:        GraphicInfo{ID: int32(i), MapID: int32(i), Width: int32(i%8 + 1), Height: int32(i%5 + 1), OffX: int32(-i), OffY: int32(-i / 2)},
+			Header:      GraphicHeader{Version: 1},
+			GraphicData: make([]byte, (i%8+1)*(i%5+1)),
+		}
+		for j := range g.GraphicData {
+			g.GraphicData[j] = byte(j % len(p))
+		}
+		for _, edit := range edits {
+			edit(g)
+		}
+
+		if _, err := w.Write(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return ib.Bytes(), gb.Bytes()
+}
+
+func TestGraphicInfo_Anchor(t *testing.T) {
+	testcases := []struct {
+		name   string
+		info   GraphicInfo
+		canvas image.Rectangle
+		anchor image.Point
+	}{
+		{
+			name:   "anchor inside graphic",
+			info:   GraphicInfo{OffX: -32, OffY: -24, Width: 64, Height: 47},
+			canvas: image.Rect(0, 0, 64, 47),
+			anchor: image.Pt(32, 24),
+		},
+		{
+			name:   "anchor above graphic",
+			info:   GraphicInfo{OffX: -2, OffY: 3, Width: 4, Height: 2},
+			canvas: image.Rect(0, 0, 4, 5),
+			anchor: image.Pt(2, 0),
+		},
+		{
+			name:   "anchor at bottom right",
+			info:   GraphicInfo{OffX: -4, OffY: -2, Width: 4, Height: 2},
+			canvas: image.Rect(0, 0, 5, 3),
+			anchor: image.Pt(4, 2),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			canvas, anchor := tc.info.Anchor()
+
+			if canvas != tc.canvas {
+				t.Errorf("expected canvas: %v, got %v", tc.canvas, canvas)
+			}
+			if anchor != tc.anchor {
+				t.Errorf("expected anchor: %v, got %v", tc.anchor, anchor)
+			}
+		})
+	}
+}
+
+func TestGraphic_ImgAnchored(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}, color.RGBA{G: 0xff, A: 0xff}}
+	g := &Graphic{
+		Info: GraphicInfo{OffX: -2, OffY: 3, Width: 2, Height: 2},
+		// bottom-up: the first row is the bottom row
+		GraphicData: []byte{1, 1, 2, 2},
+	}
+
+	rgba, anchor, err := g.ImgRGBAAnchored(palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paletted, _, err := g.ImgPalettedAnchored(palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if anchor != image.Pt(2, 0) {
+		t.Errorf("expected anchor: %v, got %v", image.Pt(2, 0), anchor)
+	}
+	if rgba.Bounds() != image.Rect(0, 0, 3, 5) || paletted.Bounds() != rgba.Bounds() {
+		t.Errorf("unexpected bounds: %v, %v", rgba.Bounds(), paletted.Bounds())
+	}
+
+	// the graphic is at anchor + (OffX, OffY) = (0, 3)
+	expect := map[image.Point]color.Color{
+		{X: 0, Y: 3}: palette[2],
+		{X: 1, Y: 3}: palette[2],
+		{X: 0, Y: 4}: palette[1],
+		{X: 1, Y: 4}: palette[1],
+		{X: 2, Y: 0}: palette[0],
+		{X: 2, Y: 4}: palette[0],
+	}
+	for pt, c := range expect {
+		if diff := cmp.Diff(color.RGBAModel.Convert(c), rgba.At(pt.X, pt.Y)); diff != "" {
+			t.Errorf("rgba %v mismatch (-want +got):\n%s", pt, diff)
+		}
+		if diff := cmp.Diff(c, paletted.At(pt.X, pt.Y)); diff != "" {
+			t.Errorf("paletted %v mismatch (-want +got):\n%s", pt, diff)
+		}
+	}
+}
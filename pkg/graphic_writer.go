@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"xgtool/internal"
+)
+
+// ErrInvalidVersion is returned when the graphic header version is not in 0-3.
+var ErrInvalidVersion = errors.New("invalid version")
+
+// GraphicWriter writes graphics into a graphic file, and the matching graphic info into a graphic info file.
+type GraphicWriter struct {
+	gif  io.Writer
+	gf   io.Writer
+	addr int32 // address of the next graphic in graphic file
+}
+
+// NewGraphicWriter creates a GraphicWriter, the graphics will be written from the beginning of gf.
+func NewGraphicWriter(gif, gf io.Writer) *GraphicWriter {
+	return &GraphicWriter{gif: gif, gf: gf}
+}
+
+// NewGraphicWriterAt creates a GraphicWriter for appending to an existing graphic file, addr is the size of the graphic file.
+func NewGraphicWriterAt(gif, gf io.Writer, addr int32) *GraphicWriter {
+	return &GraphicWriter{gif: gif, gf: gf, addr: addr}
+}
+
+// Write encodes the graphic with g.Header.Version, writes it to graphic file and writes the graphic info to graphic info file.
+//
+// The written GraphicInfo (with Addr and Len filled) is returned, g.Info.Width and g.Info.Height are used for the header.
+// When version >= 2, g.PaletteData is embedded into the graphic.
+func (w *GraphicWriter) Write(g *Graphic) (info GraphicInfo, err error) {
+	var data []byte
+	if data, err = g.encode(); err != nil {
+		return
+	}
+
+	info = g.Info
+	info.Addr = w.addr
+	info.Len = int32(len(data))
+
+	if _, err = w.gf.Write(data); err != nil {
+		return
+	}
+	if err = binary.Write(w.gif, binary.LittleEndian, info); err != nil {
+		return
+	}
+
+	w.addr += info.Len
+
+	return
+}
+
+// encode makes the raw bytes of graphic, including the header.
+func (g *Graphic) encode() (data []byte, err error) {
+	v := g.Header.Version
+	if v > 3 {
+		return nil, fmt.Errorf("%w: info=%+v, header=%+v", ErrInvalidVersion, g.Info, g.Header)
+	}
+
+	payload := g.GraphicData
+	var pal []byte
+	if v >= 2 {
+		pal = PaletteToBytes(g.PaletteData)
+		payload = append(append(make([]byte, 0, len(payload)+len(pal)), payload...), pal...)
+	}
+	if v&1 == 1 {
+		payload = internal.Encode(payload)
+	}
+
+	hsz := binary.Size(GraphicHeader{})
+	if v >= 2 {
+		hsz += 4
+	}
+
+	h := GraphicHeader{
+		Magic:   [2]byte{'R', 'D'},
+		Version: v,
+		Width:   g.Info.Width,
+		Height:  g.Info.Height,
+		Len:     int32(hsz + len(payload)),
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, int(h.Len)))
+	if err = binary.Write(buf, binary.LittleEndian, h); err != nil {
+		return
+	}
+	if v >= 2 {
+		if err = binary.Write(buf, binary.LittleEndian, int32(len(pal))); err != nil {
+			return
+		}
+	}
+	buf.Write(payload)
+
+	return buf.Bytes(), nil
+}
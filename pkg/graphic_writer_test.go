@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGraphicWriter_Write(t *testing.T) {
+	palette := color.Palette{
+		color.Transparent,
+		color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff},
+		color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+	data := []byte{0, 0, 0, 1, 1, 1, 2, 0, 1, 2, 2, 2}
+
+	graphics := []*Graphic{
+		{Info: GraphicInfo{ID: 0, Width: 4, Height: 3}, Header: GraphicHeader{Version: 0}, GraphicData: data},
+		{Info: GraphicInfo{ID: 1, Width: 4, Height: 3, MapID: 100}, Header: GraphicHeader{Version: 1}, GraphicData: data},
+		{Info: GraphicInfo{ID: 2, Width: 3, Height: 4, OffX: -1, OffY: -2}, Header: GraphicHeader{Version: 2}, GraphicData: data, PaletteData: palette},
+		{Info: GraphicInfo{ID: 3, Width: 2, Height: 6, GridW: 1, GridH: 1, Access: 1}, Header: GraphicHeader{Version: 3}, GraphicData: data, PaletteData: palette},
+	}
+
+	var gif, gf bytes.Buffer
+	w := NewGraphicWriter(&gif, &gf)
+
+	for _, g := range graphics {
+		if _, err := w.Write(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if gif.Len() != GraphicInfoSize*len(graphics) {
+		t.Errorf("expected len(gif): %d, got %d", GraphicInfoSize*len(graphics), gif.Len())
+	}
+
+	gr, err := NewGraphicResource(&gif)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range graphics {
+		got := gr.IDx.First(want.Info.ID)
+		if got == nil {
+			t.Fatalf("graphic %d not found", want.Info.ID)
+		}
+		if err = got.Load(bytes.NewReader(gf.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Info.Len != got.Header.Len {
+			t.Errorf("graphic %d: expected Info.Len == Header.Len, got %d and %d", want.Info.ID, got.Info.Len, got.Header.Len)
+		}
+		if got.Header.Version != want.Header.Version {
+			t.Errorf("graphic %d: expected version %d, got %d", want.Info.ID, want.Header.Version, got.Header.Version)
+		}
+		if diff := cmp.Diff(want.GraphicData, got.GraphicData); diff != "" {
+			t.Errorf("graphic %d: graphic data mismatch (-want +got):\n%s", want.Info.ID, diff)
+		}
+
+		wantInfo := want.Info
+		wantInfo.Addr, wantInfo.Len = got.Info.Addr, got.Info.Len
+		if diff := cmp.Diff(wantInfo, got.Info, cmp.AllowUnexported(GraphicInfo{})); diff != "" {
+			t.Errorf("graphic %d: graphic info mismatch (-want +got):\n%s", want.Info.ID, diff)
+		}
+
+		if want.Header.Version >= 2 {
+			if diff := cmp.Diff(want.PaletteData, got.PaletteData); diff != "" {
+				t.Errorf("graphic %d: palette mismatch (-want +got):\n%s", want.Info.ID, diff)
+			}
+		} else if len(got.PaletteData) != 0 {
+			t.Errorf("graphic %d: expected empty palette, got %d colors", want.Info.ID, len(got.PaletteData))
+		}
+	}
+}
+
+func TestGraphicWriter_Write_InvalidVersion(t *testing.T) {
+	w := NewGraphicWriter(io.Discard, io.Discard)
+
+	_, err := w.Write(&Graphic{Header: GraphicHeader{Version: 4}})
+	if !errors.Is(err, ErrInvalidVersion) {
+		t.Errorf("expected error: %v, got: %v", ErrInvalidVersion, err)
+	}
+}
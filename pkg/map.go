@@ -9,12 +9,17 @@ import (
 	"image/color"
 	"image/png"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"xgtool/internal/mat"
 	"xgtool/internal/tmx"
 )
 
+// ErrInvalidMap is returned when the size of blocks doesn't match the width and height of map.
+var ErrInvalidMap = errors.New("invalid map")
+
 type mapHeader struct {
 	Magic  [12]byte
 	Width  int32
@@ -35,6 +40,17 @@ const (
 	TileHeight = 47
 )
 
+// NewMap creates an empty Map of w columns and h rows.
+func NewMap(w, h int) (m Map) {
+	copy(m.Header.Magic[:], "MAP")
+	m.Header.Width, m.Header.Height = int32(w), int32(h)
+	m.Ground = make([]uint16, w*h)
+	m.Object = make([]uint16, w*h)
+	m.Meta = make([]uint16, w*h)
+
+	return
+}
+
 // MakeMap make a Map from Crossgate map file.
 func MakeMap(f io.Reader) (m Map, err error) {
 	if m.Header, err = readHeader(f); err != nil {
@@ -66,7 +82,7 @@ func readHeader(f io.Reader) (h mapHeader, err error) {
 		return
 	}
 
-	if !(h.Magic[0] == 'M' || h.Magic[1] == 'A' || h.Magic[2] == 'P') {
+	if !(h.Magic[0] == 'M' && h.Magic[1] == 'A' && h.Magic[2] == 'P') {
 		err = fmt.Errorf("%w: header=%+v", ErrInvalidMagic, h)
 	}
 
@@ -93,8 +109,37 @@ func readBlock(f io.Reader, len int) (b []uint16, err error) {
 	return
 }
 
-// TiledMap convert the Map to a tmx.Map
-func (m Map) TiledMap(index GraphicIndex, gf io.ReadSeeker, p color.Palette, outdir string) (tiled tmx.Map, err error) {
+// Write writes m in the format of CrossGate map file, which is read by MakeMap.
+func (m Map) Write(w io.Writer) (err error) {
+	n := int(m.Header.Width * m.Header.Height)
+	if len(m.Ground) != n || len(m.Object) != n || len(m.Meta) != n {
+		return fmt.Errorf(
+			"%w: width=%d, height=%d, len(ground)=%d, len(object)=%d, len(meta)=%d",
+			ErrInvalidMap, m.Header.Width, m.Header.Height, len(m.Ground), len(m.Object), len(m.Meta),
+		)
+	}
+
+	for _, data := range []any{m.Header, m.Ground, m.Object, m.Meta} {
+		if err = binary.Write(w, binary.LittleEndian, data); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// TiledMap convert the Map to a tmx.Map, the tile images are rendered to outdir. The collision tile set refers to the
+// image collision, which is written by WriteCollisionTile, and its path is relative to outdir.
+func (m Map) TiledMap(index GraphicIndex, gf io.ReadSeeker, p color.Palette, outdir, collision string) (tiled tmx.Map, err error) {
+	return m.tiledMap(index, collision, func(name string, fgid *int, tiles []uint16) (tmx.TileSet, error) {
+		return m.buildTileSet(name, fgid, tiles, index, gf, p, outdir)
+	})
+}
+
+// tileSetBuilder builds the tile set of tiles after *fgid, and sets *fgid to its last GID.
+type tileSetBuilder func(name string, fgid *int, tiles []uint16) (tmx.TileSet, error)
+
+func (m Map) tiledMap(index GraphicIndex, collision string, build tileSetBuilder) (tiled tmx.Map, err error) {
 	tiled = tmx.NewMap(
 		// reverse width and height, because the map will be rotated -90 degrees
 		int(m.Header.Height),
@@ -102,14 +147,18 @@ func (m Map) TiledMap(index GraphicIndex, gf io.ReadSeeker, p color.Palette, out
 		tmx.Isometric,
 		tmx.LeftUp,
 	)
-	tiled.Layers = make([]tmx.Layer, 0, 2)
-	tiled.TileSets = make([]tmx.TileSet, 0, 2)
+	tiled.Layers = make([]tmx.Layer, 0, 4)
+	tiled.TileSets = make([]tmx.TileSet, 0, 3)
 
 	var gid int
-	if gid, err = m.setGround(&tiled, index, gf, p, outdir); err != nil {
+	if gid, err = m.setGround(&tiled, build); err != nil {
 		return
 	}
-	if err = m.setObject(&tiled, gid, index, gf, p, outdir); err != nil {
+	if gid, err = m.setObject(&tiled, gid, index, build); err != nil {
+		return
+	}
+	m.setMeta(&tiled)
+	if err = m.setCollision(&tiled, gid, index, collision); err != nil {
 		return
 	}
 
@@ -119,23 +168,27 @@ func (m Map) TiledMap(index GraphicIndex, gf io.ReadSeeker, p color.Palette, out
 	return
 }
 
-func (m Map) setGround(tiled *tmx.Map, index GraphicIndex, gf io.ReadSeeker, p color.Palette, outdir string) (gid int, err error) {
-	var layer tmx.Layer
-	if layer, err = m.buildGroundLayer(); err != nil {
+// setGround appends the ground layer and its tile set, the tile set is built first to mark the tiles not found in the
+// index as empty.
+func (m Map) setGround(tiled *tmx.Map, build tileSetBuilder) (gid int, err error) {
+	var tileset tmx.TileSet
+	if tileset, err = build("ground", &gid, m.Ground); err != nil {
 		return
 	}
-	tiled.Layers = append(tiled.Layers, layer)
+	tiled.TileSets = append(tiled.TileSets, tileset)
 
-	var tileset tmx.TileSet
-	if tileset, err = m.buildTileSet("ground", &gid, m.Ground, index, gf, p, outdir); err != nil {
+	var layer tmx.Layer
+	if layer, err = m.buildGroundLayer(tileGIDs(tileset)); err != nil {
 		return
 	}
-	tiled.TileSets = append(tiled.TileSets, tileset)
+	tiled.Layers = append(tiled.Layers, layer)
 
 	return
 }
 
-func (m Map) setObject(tiled *tmx.Map, gid int, index GraphicIndex, gf io.ReadSeeker, p color.Palette, outdir string) (err error) {
+func (m Map) setObject(tiled *tmx.Map, fgid int, index GraphicIndex, build tileSetBuilder) (gid int, err error) {
+	gid = fgid
+
 	var layer tmx.Layer
 	if layer, err = m.buildObjectLayer(index, gid); err != nil {
 		return
@@ -143,7 +196,7 @@ func (m Map) setObject(tiled *tmx.Map, gid int, index GraphicIndex, gf io.ReadSe
 	tiled.Layers = append(tiled.Layers, layer)
 
 	var tileset tmx.TileSet
-	if tileset, err = m.buildTileSet("object", &gid, m.Object, index, gf, p, outdir); err != nil {
+	if tileset, err = build("object", &gid, m.Object); err != nil {
 		return
 	}
 	tiled.TileSets = append(tiled.TileSets, tileset)
@@ -151,7 +204,40 @@ func (m Map) setObject(tiled *tmx.Map, gid int, index GraphicIndex, gf io.ReadSe
 	return
 }
 
-func (m Map) buildGroundLayer() (layer tmx.Layer, err error) {
+// setCollision appends the collision layer of Map.Walkability, and its tileset after gid, which has only one tile of
+// the image collision.
+func (m Map) setCollision(tiled *tmx.Map, gid int, index GraphicIndex, collision string) (err error) {
+	gid++
+
+	var layer tmx.Layer
+	if layer, err = m.Walkability(index).buildCollisionLayer(gid); err != nil {
+		return
+	}
+	tiled.Layers = append(tiled.Layers, layer)
+
+	ts := tmx.NewTileSet("collision", gid, tmx.Grid{Orientation: tmx.Orthogonal, Width: 1, Height: 1})
+	ts.TileCount = 1
+	ts.TileWidth, ts.TileHeight = TileWidth, TileHeight
+	ts.Tiles = []tmx.Tile{{ID: 0, Image: filepath.ToSlash(collision), ImageWidth: TileWidth, ImageHeight: TileHeight}}
+	tiled.TileSets = append(tiled.TileSets, ts)
+
+	return
+}
+
+// setMeta appends the meta layer, its object IDs follow the objects in other layers.
+func (m Map) setMeta(tiled *tmx.Map) {
+	id := 1
+	for _, l := range tiled.Layers {
+		for _, o := range l.Objects {
+			id = max(id, o.ID+1)
+		}
+	}
+
+	tiled.Layers = append(tiled.Layers, m.buildMetaLayer(id))
+}
+
+// buildGroundLayer creates the ground layer, the GIDs of MapIDs are gids, or the MapIDs themselves if gids is nil.
+func (m Map) buildGroundLayer(gids map[uint16]int) (layer tmx.Layer, err error) {
 	layer = tmx.NewTileLayer(
 		"ground",
 		1,
@@ -169,12 +255,37 @@ func (m Map) buildGroundLayer() (layer tmx.Layer, err error) {
 	tile = matrix.Rotate().ToUint16Array()
 
 	for _, t := range tile {
-		layer.Data = append(layer.Data, uint(t))
+		if gids != nil {
+			layer.Data = append(layer.Data, uint(gids[t]))
+		} else {
+			layer.Data = append(layer.Data, uint(t))
+		}
+	}
+
+	return
+}
+
+// tileGIDs returns the GID of each MapID in ts.
+func tileGIDs(ts tmx.TileSet) (gids map[uint16]int) {
+	gids = make(map[uint16]int, len(ts.Tiles))
+	for _, t := range ts.Tiles {
+		gids[uint16(tileMapID(t))] = ts.FirstGID + t.ID
 	}
 
 	return
 }
 
+// tileMapID returns the MapID of t, which is the tile property "MapID" of grid tile set, or the tile ID + 1.
+func tileMapID(t tmx.Tile) int {
+	for _, p := range t.Properties {
+		if v, ok := intValue(p.Value); ok && p.Name == "MapID" {
+			return v
+		}
+	}
+
+	return t.ID + 1
+}
+
 func (m Map) buildObjectLayer(index GraphicIndex, fgid int) (layer tmx.Layer, err error) {
 	layer = tmx.NewObjectLayer("object", 2, tmx.TopDown)
 
@@ -201,9 +312,7 @@ func (m Map) buildObjectLayer(index GraphicIndex, fgid int) (layer tmx.Layer, er
 // Ref: https://github.com/x-gate/CrossGateRemastered/blob/master/toolchain/hackMap/getCGMap.cpp#L304-L374
 func objectCoordinate(i, mapWidth, w, h, offX, offY int32) (x, y float64) {
 	row, col := i/mapWidth, i%mapWidth
-
-	offsetX := (float64(offX) + (float64(w) / 2.0)) / TileWidth * TileHeight
-	offsetY := float64(offY) + float64(h) - TileHeight/2.0
+	offsetX, offsetY := objectOffset(w, h, offX, offY)
 
 	x = float64(row+1)*TileHeight + offsetY + offsetX
 	y = float64(mapWidth-col)*TileHeight + offsetY - offsetX
@@ -211,11 +320,53 @@ func objectCoordinate(i, mapWidth, w, h, offX, offY int32) (x, y float64) {
 	return
 }
 
+// objectCell gets the cell (col, row) from the (X, Y) of object, it's the inverse of objectCoordinate, and (X, Y) is
+// rounded to the nearest cell.
+func objectCell(x, y float64, mapWidth, w, h, offX, offY int32) (col, row int) {
+	offsetX, offsetY := objectOffset(w, h, offX, offY)
+
+	row = int(math.Round((x-offsetY-offsetX)/TileHeight)) - 1
+	col = int(mapWidth) - int(math.Round((y-offsetY+offsetX)/TileHeight))
+
+	return
+}
+
+func objectOffset(w, h, offX, offY int32) (offsetX, offsetY float64) {
+	offsetX = (float64(offX) + (float64(w) / 2.0)) / TileWidth * TileHeight
+	offsetY = float64(offY) + float64(h) - TileHeight/2.0
+
+	return
+}
+
 func (m Map) buildTileSet(name string, fgid *int, tiles []uint16, index GraphicIndex, gf io.ReadSeeker, p color.Palette, outdir string) (ts tmx.TileSet, err error) {
 	*fgid++
 	ts = tmx.NewTileSet(name, *fgid, tmx.Grid{Orientation: tmx.Orthogonal, Width: 1, Height: 1})
 
-	mapping := make(map[uint16]GraphicInfo)
+	for _, t := range usedTiles(tiles, index) {
+		info := index.First(int32(t)).Info
+		if err = render(info, gf, p, outdir); err != nil {
+			return
+		}
+
+		ts.TileCount++
+		ts.Tiles = append(ts.Tiles, tmx.Tile{
+			ID:          int(t) - 1,
+			Image:       fmt.Sprintf("%d.png", t),
+			ImageWidth:  int(info.Width),
+			ImageHeight: int(info.Height),
+		})
+		// the tile ID is MapID - 1, so the last GID of this tile set is FirstGID + MapID - 1
+		*fgid = max(*fgid, ts.FirstGID+int(t)-1)
+	}
+	ts.TileWidth, ts.TileHeight = TileWidth, TileHeight
+
+	return
+}
+
+// usedTiles returns the distinct MapIDs of tiles in ascending order, the tiles not found in the index are marked as
+// empty.
+func usedTiles(tiles []uint16, index GraphicIndex) (ids []uint16) {
+	used := make(map[uint16]bool)
 	for i, t := range tiles {
 		// When the tile is 0, means empty.
 		if t == 0 {
@@ -227,30 +378,16 @@ func (m Map) buildTileSet(name string, fgid *int, tiles []uint16, index GraphicI
 			continue
 		}
 		// When the tile is found in the map, ignore it because it has been processed.
-		if _, ok := mapping[t]; ok {
+		if used[t] {
 			continue
 		}
 
-		mapping[t] = index.First(int32(t)).Info
-		if t > uint16(*fgid) {
-			*fgid = int(t)
-		}
-
-		if err = render(mapping[t], gf, p, outdir); err != nil {
-			return
-		}
-	}
-
-	for _, v := range mapping {
-		ts.TileCount++
-		ts.Tiles = append(ts.Tiles, tmx.Tile{
-			ID:          int(v.MapID) - 1,
-			Image:       fmt.Sprintf("%d.png", v.MapID),
-			ImageWidth:  int(v.Width),
-			ImageHeight: int(v.Height),
-		})
+		used[t] = true
+		ids = append(ids, t)
 	}
-	ts.TileWidth, ts.TileHeight = TileWidth, TileHeight
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
 
 	return
 }
@@ -0,0 +1,156 @@
+package pkg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"xgtool/internal/atlas"
+	"xgtool/internal/tmx"
+)
+
+// atlasSpacing is the spacing between tiles in the atlas images, it avoids bleeding of the neighbor tiles when scaled.
+const atlasSpacing = 1
+
+// TiledMapAtlas converts the Map to a tmx.Map like TiledMap, but the graphics of ground and object tile sets are
+// packed into the atlas images "ground.png" and "object.png" in outdir, instead of one image per tile.
+//
+// The ground tile set is a fixed grid of the atlas, see buildGridTileSet. The object tile set keeps a sub-rectangle
+// (X, Y, Width, Height) of the atlas for each tile, because the graphics of objects have variable sizes, and its tile
+// ID is MapID - 1. MakeMapFromTiled works with both.
+func (m Map) TiledMapAtlas(index GraphicIndex, gf io.ReaderAt, p color.Palette, outdir, collision string) (tiled tmx.Map, err error) {
+	return m.tiledMap(index, collision, func(name string, fgid *int, tiles []uint16) (tmx.TileSet, error) {
+		if name == "ground" {
+			return m.buildGridTileSet(name, fgid, tiles, index, gf, p, outdir)
+		}
+
+		return m.buildAtlasTileSet(name, fgid, tiles, index, gf, p, outdir)
+	})
+}
+
+// buildGridTileSet packs the graphics of tiles into the grid atlas "<name>.png". The cells are squares of the largest
+// graphic, so the atlas can be used as the grid tile set of LDtk as well. Each graphic is at the bottom left of its
+// cell, where Tiled aligns the tiles of tile layer, so the tiles are drawn at the same position as TiledMap.
+//
+// The tile ID is the index of cell, and the MapID is the tile property "MapID".
+func (m Map) buildGridTileSet(name string, fgid *int, tiles []uint16, index GraphicIndex, gf io.ReaderAt, p color.Palette, outdir string) (ts tmx.TileSet, err error) {
+	*fgid++
+	ts = tmx.NewTileSet(name, *fgid, tmx.Grid{Orientation: tmx.Orthogonal, Width: 1, Height: 1})
+	ts.TileWidth, ts.TileHeight = TileWidth, TileHeight
+	ts.TileOffset = &tmx.TileOffset{}
+
+	ids := usedTiles(tiles, index)
+	if len(ids) == 0 {
+		return
+	}
+
+	var imgs []*image.RGBA
+	if imgs, err = loadTileImages(ids, index, gf, p); err != nil {
+		return
+	}
+
+	cell := 0
+	for _, img := range imgs {
+		cell = max(cell, img.Rect.Dx(), img.Rect.Dy())
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(len(ids)))))
+	rows := (len(ids) + cols - 1) / cols
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*(cell+atlasSpacing)-atlasSpacing, rows*(cell+atlasSpacing)-atlasSpacing))
+
+	ts.Image = fmt.Sprintf("%s.png", name)
+	ts.ImageWidth, ts.ImageHeight = sheet.Rect.Dx(), sheet.Rect.Dy()
+	ts.TileWidth, ts.TileHeight = cell, cell
+	ts.Columns, ts.Spacing = cols, atlasSpacing
+	ts.TileCount = len(ids)
+	for i, t := range ids {
+		at := image.Pt(i%cols*(cell+atlasSpacing), i/cols*(cell+atlasSpacing)+cell-imgs[i].Rect.Dy())
+		draw.Draw(sheet, imgs[i].Rect.Sub(imgs[i].Rect.Min).Add(at), imgs[i], imgs[i].Rect.Min, draw.Src)
+
+		ts.Tiles = append(ts.Tiles, tmx.Tile{
+			ID:         i,
+			Properties: []tmx.Property{{Name: "MapID", Type: "int", Value: int(t)}},
+		})
+	}
+	*fgid = ts.FirstGID + len(ids) - 1
+
+	return ts, writePNG(filepath.Join(outdir, ts.Image), sheet)
+}
+
+// buildAtlasTileSet packs the graphics of tiles into the atlas "<name>.png", each tile is a sub-rectangle of the
+// atlas in the size of its graphic. The tile ID is MapID - 1, and the tiles have no offset, because the tile objects
+// are placed by objectCoordinate.
+func (m Map) buildAtlasTileSet(name string, fgid *int, tiles []uint16, index GraphicIndex, gf io.ReaderAt, p color.Palette, outdir string) (ts tmx.TileSet, err error) {
+	*fgid++
+	ts = tmx.NewTileSet(name, *fgid, tmx.Grid{Orientation: tmx.Orthogonal, Width: 1, Height: 1})
+	ts.TileWidth, ts.TileHeight = TileWidth, TileHeight
+	ts.TileOffset = &tmx.TileOffset{}
+
+	ids := usedTiles(tiles, index)
+	if len(ids) == 0 {
+		return
+	}
+
+	var imgs []*image.RGBA
+	if imgs, err = loadTileImages(ids, index, gf, p); err != nil {
+		return
+	}
+	sizes := make([]image.Point, len(imgs))
+	for i, img := range imgs {
+		sizes[i] = img.Bounds().Size()
+	}
+
+	rects, size := atlas.Pack(sizes, atlasSpacing)
+	sheet := image.NewRGBA(image.Rectangle{Max: size})
+	file := fmt.Sprintf("%s.png", name)
+
+	for i, t := range ids {
+		draw.Draw(sheet, rects[i], imgs[i], imgs[i].Bounds().Min, draw.Src)
+
+		ts.TileCount++
+		ts.Tiles = append(ts.Tiles, tmx.Tile{
+			ID:          int(t) - 1,
+			Image:       file,
+			ImageWidth:  size.X,
+			ImageHeight: size.Y,
+			X:           rects[i].Min.X,
+			Y:           rects[i].Min.Y,
+			Width:       rects[i].Dx(),
+			Height:      rects[i].Dy(),
+		})
+		// the tile ID is MapID - 1, so the last GID of this tile set is FirstGID + MapID - 1
+		*fgid = max(*fgid, ts.FirstGID+int(t)-1)
+	}
+
+	return ts, writePNG(filepath.Join(outdir, file), sheet)
+}
+
+// loadTileImages loads the graphics of MapIDs in ids.
+func loadTileImages(ids []uint16, index GraphicIndex, gf io.ReaderAt, p color.Palette) (imgs []*image.RGBA, err error) {
+	imgs = make([]*image.RGBA, len(ids))
+	for i, t := range ids {
+		var g *Graphic
+		if g, err = index.First(int32(t)).Info.LoadGraphicAt(gf); err != nil {
+			return
+		}
+		if imgs[i], err = g.ImgRGBA(p); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func writePNG(name string, img image.Image) (err error) {
+	var out *os.File
+	if out, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644); err != nil {
+		return
+	}
+	defer out.Close()
+
+	return png.Encode(out, img)
+}
@@ -0,0 +1,177 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"xgtool/internal/tmx"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestMap_TiledMapAtlas(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}, color.RGBA{G: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 5, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newMap := func() Map {
+		m := NewMap(4, 3)
+		m.Ground = []uint16{1, 2, 3, 4, 4, 3, 2, 1, 1, 1, 2, 9}
+		m.Object = []uint16{0, 3, 0, 0, 0, 0, 0, 5, 2, 0, 0, 0}
+		m.Meta = []uint16{0, 1, 0, 0, 0, 0, 0x12, 0, 0, 0, 0, 6}
+		return m
+	}
+
+	outdir := t.TempDir()
+	tm, err := newMap().TiledMapAtlas(gr.MDx, bytes.NewReader(gf), palette, outdir, CollisionImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same layers as TiledMap, only the GIDs and the tile images differ
+	want, err := newMap().TiledMap(gr.MDx, bytes.NewReader(gf), palette, t.TempDir(), CollisionImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want.Layers[1:3], tm.Layers[1:3], cmpopts.IgnoreFields(tmx.Object{}, "GID")); diff != "" {
+		t.Errorf("layers mismatch (-want +got):\n%s", diff)
+	}
+	last := 0
+	for _, ts := range tm.TileSets {
+		if ts.FirstGID <= last {
+			t.Errorf("tile set %s: FirstGID = %d, overlaps the previous tile set ending at %d", ts.Name, ts.FirstGID, last)
+		}
+		last = ts.FirstGID + ts.TileCount - 1
+		for _, tile := range ts.Tiles {
+			last = max(last, ts.FirstGID+tile.ID)
+		}
+	}
+
+	ground := tm.TileSets[0]
+	if ground.Image != "ground.png" || ground.Columns != 2 || ground.Spacing != atlasSpacing || ground.TileCount != 4 {
+		t.Errorf("ground: Image = %s, Columns = %d, Spacing = %d, TileCount = %d", ground.Image, ground.Columns, ground.Spacing, ground.TileCount)
+	}
+	if diff := cmp.Diff(&tmx.TileOffset{}, ground.TileOffset); diff != "" {
+		t.Errorf("ground TileOffset mismatch (-want +got):\n%s", diff)
+	}
+	sheet := readPNG(t, filepath.Join(outdir, ground.Image))
+	if sheet.Bounds().Dx() != ground.ImageWidth || sheet.Bounds().Dy() != ground.ImageHeight {
+		t.Errorf("ground: image size = %v, want %dx%d", sheet.Bounds().Size(), ground.ImageWidth, ground.ImageHeight)
+	}
+	for _, tile := range ground.Tiles {
+		// the graphic is at the bottom left of its cell
+		img := loadTestGraphic(t, gr.MDx, gf, palette, tileMapID(tile))
+		at := image.Pt(tile.ID%ground.Columns*(ground.TileWidth+ground.Spacing), tile.ID/ground.Columns*(ground.TileHeight+ground.Spacing)+ground.TileHeight-img.Bounds().Dy())
+		comparePixels(t, fmt.Sprintf("ground tile %d", tile.ID), sheet, image.Rectangle{Min: at, Max: at.Add(img.Bounds().Size())}, img)
+	}
+	// the GIDs of TiledMap are MapIDs
+	for i, gid := range tm.Layers[0].Data {
+		id := 0
+		if gid != 0 {
+			id = tileMapID(ground.Tiles[int(gid)-ground.FirstGID])
+		}
+		if id != int(want.Layers[0].Data[i]) {
+			t.Errorf("ground cell %d: GID = %d, MapID = %d, want %d", i, gid, id, want.Layers[0].Data[i])
+		}
+	}
+
+	object := tm.TileSets[1]
+	if diff := cmp.Diff(&tmx.TileOffset{}, object.TileOffset); diff != "" {
+		t.Errorf("object TileOffset mismatch (-want +got):\n%s", diff)
+	}
+	sheet = readPNG(t, filepath.Join(outdir, "object.png"))
+	var rects []image.Rectangle
+	for _, tile := range object.Tiles {
+		if tile.Image != "object.png" || tile.ImageWidth != sheet.Bounds().Dx() || tile.ImageHeight != sheet.Bounds().Dy() {
+			t.Errorf("object tile %d: image = %s %dx%d", tile.ID, tile.Image, tile.ImageWidth, tile.ImageHeight)
+		}
+
+		r := image.Rect(tile.X, tile.Y, tile.X+tile.Width, tile.Y+tile.Height)
+		for _, o := range rects {
+			if r.Overlaps(o) {
+				t.Errorf("object tile %d: %v overlaps %v", tile.ID, r, o)
+			}
+		}
+		rects = append(rects, r)
+
+		comparePixels(t, fmt.Sprintf("object tile %d", tile.ID), sheet, r, loadTestGraphic(t, gr.MDx, gf, palette, tile.ID+1))
+	}
+
+	b, err := json.Marshal(tm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded tmx.Map
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	got, err := MakeMapFromTiled(decoded, gr.MDx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMap := newMap()
+	wantMap.Ground[11] = 0 // not in index
+	if diff := cmp.Diff(wantMap, got, cmp.AllowUnexported(Map{})); diff != "" {
+		t.Errorf("MakeMapFromTiled() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// loadTestGraphic loads the image of mapID in index.
+func loadTestGraphic(t *testing.T, index GraphicIndex, gf []byte, palette color.Palette, mapID int) image.Image {
+	t.Helper()
+
+	g, err := index.First(int32(mapID)).Info.LoadGraphicAt(bytes.NewReader(gf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := g.ImgRGBA(palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
+
+// comparePixels compares the pixels of r in sheet with img.
+func comparePixels(t *testing.T, name string, sheet image.Image, r image.Rectangle, img image.Image) {
+	t.Helper()
+
+	if r.Size() != img.Bounds().Size() {
+		t.Fatalf("%s: size = %v, want %v", name, r.Size(), img.Bounds().Size())
+	}
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			if got, want := color.RGBAModel.Convert(sheet.At(r.Min.X+x, r.Min.Y+y)), img.At(x, y); got != want {
+				t.Fatalf("%s: pixel (%d, %d) = %v, want %v", name, x, y, got, want)
+			}
+		}
+	}
+}
+
+func readPNG(t *testing.T, name string) image.Image {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img
+}
@@ -0,0 +1,167 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"xgtool/internal/mat"
+	"xgtool/internal/tmx"
+)
+
+// ErrInvalidTiledMap is returned when a tmx.Map can't be converted back to Map.
+var ErrInvalidTiledMap = errors.New("invalid tiled map")
+
+// gidMask clears the flipping flags in the high bits of GID, CrossGate maps don't flip tiles.
+const gidMask = 0x0fffffff
+
+// MakeMapFromTiled converts tm back to Map, it's the reverse of Map.TiledMap.
+//
+// The layers are found by name: the tile layer "ground" is rotated back 90 degrees, the tile objects in object layer
+// "object" are put back to their cells by the graphics in index, and the attributes in object layer "meta" are
+// encoded back. The other layers, such as "collision", are ignored. GIDs are converted to MapIDs by the FirstGID of
+// tile sets, the tile ID is MapID - 1 as Map.TiledMap does, unless the tile has the property "MapID".
+func MakeMapFromTiled(tm tmx.Map, index GraphicIndex) (m Map, err error) {
+	if tm.Infinite {
+		return m, fmt.Errorf("%w: infinite map is not supported", ErrInvalidTiledMap)
+	}
+
+	// reverse width and height, because the map was rotated -90 degrees
+	m = NewMap(tm.Height, tm.Width)
+	ids := newMapIDs(tm.TileSets)
+
+	for _, l := range tm.Layers {
+		switch {
+		case l.Name == "ground" && l.Type == tmx.TileLayer:
+			err = m.importGround(l, ids)
+		case l.Name == "object" && l.Type == tmx.ObjectGroup:
+			err = m.importObject(l, ids, index)
+		case l.Name == "meta" && l.Type == tmx.ObjectGroup:
+			err = m.importMeta(l)
+		}
+		if err != nil {
+			return m, fmt.Errorf("layer %q: %w", l.Name, err)
+		}
+	}
+
+	return
+}
+
+func (m *Map) importGround(l tmx.Layer, ids mapIDs) (err error) {
+	if l.Width != int(m.Header.Height) || l.Height != int(m.Header.Width) {
+		return fmt.Errorf("%w: layer=%dx%d, map=%dx%d", ErrInvalidTiledMap, l.Width, l.Height, m.Header.Height, m.Header.Width)
+	}
+
+	var matrix mat.Matrix
+	if matrix, err = mat.NewMatrix(l.Data, l.Width, l.Height); err != nil {
+		return
+	}
+
+	for i, gid := range matrix.RotateBack().Data {
+		if m.Ground[i], err = ids.mapID(gid); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// importObject puts the tile objects back to the nearest cells, the objects without GID are ignored.
+func (m *Map) importObject(l tmx.Layer, ids mapIDs, index GraphicIndex) (err error) {
+	for _, o := range l.Objects {
+		if o.GID == 0 {
+			continue
+		}
+
+		var id uint16
+		if id, err = ids.mapID(o.GID); err != nil {
+			return
+		}
+
+		g := index.First(int32(id))
+		if g == nil {
+			return fmt.Errorf("%w: object=%d, map id=%d", ErrGraphicNotFound, o.ID, id)
+		}
+
+		col, row := objectCell(o.X, o.Y, m.Header.Width, g.Info.Width, g.Info.Height, g.Info.OffX, g.Info.OffY)
+		if err = m.set(m.Object, col, row, id); err != nil {
+			return fmt.Errorf("object=%d: %w", o.ID, err)
+		}
+	}
+
+	return
+}
+
+// importMeta encodes the attributes back from the objects of buildMetaLayer.
+func (m *Map) importMeta(l tmx.Layer) (err error) {
+	for _, o := range l.Objects {
+		col := int(m.Header.Width) - 1 - int(math.Round(o.Y/TileHeight))
+		row := int(math.Round(o.X / TileHeight))
+
+		if err = m.set(m.Meta, col, row, uint16(metaFromProperties(o.Properties))); err != nil {
+			return fmt.Errorf("object=%d: %w", o.ID, err)
+		}
+	}
+
+	return
+}
+
+// set sets the cell (col, row) of block to v.
+func (m *Map) set(block []uint16, col, row int, v uint16) error {
+	w, h := int(m.Header.Width), int(m.Header.Height)
+	if col < 0 || col >= w || row < 0 || row >= h {
+		return fmt.Errorf("%w: cell (%d, %d) is out of map %dx%d", ErrInvalidTiledMap, col, row, w, h)
+	}
+	block[row*w+col] = v
+
+	return nil
+}
+
+// mapIDs converts GIDs to MapIDs, the tile sets are sorted by FirstGID in descending order.
+type mapIDs []tileSetIDs
+
+// tileSetIDs is the MapIDs of tiles with the property "MapID" in a tile set, the others are tile ID + 1.
+type tileSetIDs struct {
+	firstGID int
+	ids      map[int]int
+}
+
+func newMapIDs(tilesets []tmx.TileSet) (ids mapIDs) {
+	for _, ts := range tilesets {
+		tids := tileSetIDs{firstGID: ts.FirstGID, ids: make(map[int]int)}
+		for _, t := range ts.Tiles {
+			tids.ids[t.ID] = tileMapID(t)
+		}
+		ids = append(ids, tids)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].firstGID > ids[j].firstGID
+	})
+
+	return
+}
+
+// mapID returns the MapID of gid in the tile set with the largest FirstGID not greater than gid, 0 is empty.
+func (ids mapIDs) mapID(gid int) (id uint16, err error) {
+	if gid &= gidMask; gid == 0 {
+		return
+	}
+
+	for _, ts := range ids {
+		if gid < ts.firstGID {
+			continue
+		}
+
+		v, ok := ts.ids[gid-ts.firstGID]
+		if !ok {
+			v = gid - ts.firstGID + 1
+		}
+		if v <= 0 || v > math.MaxUint16 {
+			return 0, fmt.Errorf("%w: gid=%d, map id=%d is out of range", ErrInvalidTiledMap, gid, v)
+		}
+
+		return uint16(v), nil
+	}
+
+	return 0, fmt.Errorf("%w: gid=%d is not in any tile set", ErrInvalidTiledMap, gid)
+}
@@ -0,0 +1,226 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image/color"
+	"testing"
+	"xgtool/internal/tmx"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMap_Write(t *testing.T) {
+	m := NewMap(3, 2)
+	m.Ground = []uint16{1, 2, 3, 4, 5, 6}
+	m.Object = []uint16{0, 0, 7, 0, 8, 0}
+	m.Meta = []uint16{1, 0, 0, 2, 0, 0xf0}
+
+	buf := new(bytes.Buffer)
+	if err := m.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 20+3*6*2 {
+		t.Errorf("len(Write()) = %d, want %d", buf.Len(), 20+3*6*2)
+	}
+
+	got, err := MakeMap(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(m, got, cmp.AllowUnexported(Map{})); diff != "" {
+		t.Errorf("MakeMap() mismatch (-want +got):\n%s", diff)
+	}
+
+	m.Meta = m.Meta[:5]
+	if err = m.Write(new(bytes.Buffer)); !errors.Is(err, ErrInvalidMap) {
+		t.Errorf("Write() error = %v, wantErr %v", err, ErrInvalidMap)
+	}
+}
+
+func TestObjectCell(t *testing.T) {
+	const mapWidth = 5
+
+	infos := []GraphicInfo{
+		{Width: 64, Height: 47, OffX: -32, OffY: -24},
+		{Width: 10, Height: 7, OffX: -3, OffY: -2},
+		{Width: 200, Height: 300, OffX: -64, OffY: -250},
+	}
+	for _, gi := range infos {
+		for i := int32(0); i < mapWidth*4; i++ {
+			x, y := objectCoordinate(i, mapWidth, gi.Width, gi.Height, gi.OffX, gi.OffY)
+			col, row := objectCell(x, y, mapWidth, gi.Width, gi.Height, gi.OffX, gi.OffY)
+			if col != int(i%mapWidth) || row != int(i/mapWidth) {
+				t.Errorf("objectCell(%v, %v) = (%d, %d), want (%d, %d)", x, y, col, row, i%mapWidth, i/mapWidth)
+			}
+		}
+	}
+}
+
+func TestMakeMapFromTiled(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 4, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMap(4, 3)
+	m.Ground = []uint16{1, 2, 3, 4, 4, 3, 2, 1, 1, 1, 2, 2}
+	m.Object = []uint16{0, 3, 0, 0, 0, 0, 0, 4, 2, 0, 0, 0}
+	m.Meta = []uint16{0, 1, 0, 0, 0, 0, 0x12, 0, 0, 0, 0, 6}
+
+	// TiledMap modifies the blocks, so pass a copy
+	cp := NewMap(4, 3)
+	copy(cp.Ground, m.Ground)
+	copy(cp.Object, m.Object)
+	copy(cp.Meta, m.Meta)
+
+	tm, err := cp.TiledMap(gr.MDx, bytes.NewReader(gf), palette, t.TempDir(), CollisionImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// read back from JSON like import-map does
+	b, err := json.Marshal(tm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(b, &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := MakeMapFromTiled(tm, gr.MDx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(m, got, cmp.AllowUnexported(Map{})); diff != "" {
+		t.Errorf("MakeMapFromTiled() mismatch (-want +got):\n%s", diff)
+	}
+
+	// edit in Tiled: nudge an object, and change the bits of cell (2, 1) in meta from 0x12 to 0x3
+	for i, l := range tm.Layers {
+		switch l.Name {
+		case "object":
+			tm.Layers[i].Objects[0].X += 5
+			tm.Layers[i].Objects[0].Y -= 5
+		case "meta":
+			for j, o := range l.Objects {
+				if o.X != 1*TileHeight || o.Y != (4-1-2)*TileHeight {
+					continue
+				}
+				for k, p := range o.Properties {
+					if p.Name == "bit4" {
+						tm.Layers[i].Objects[j].Properties[k].Value = false
+					}
+				}
+				tm.Layers[i].Objects[j].Properties = append(o.Properties, tmx.Property{Name: "bit0", Type: "bool", Value: true})
+			}
+		}
+	}
+	m.Meta[1*4+2] = 0x3
+
+	if got, err = MakeMapFromTiled(tm, gr.MDx); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(m, got, cmp.AllowUnexported(Map{})); diff != "" {
+		t.Errorf("MakeMapFromTiled() after editing mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMakeMapFromTiled_Error(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}}
+	gi, _ := writeGraphics(t, 2, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ground := tmx.NewTileLayer("ground", 1, 2, 1)
+	ground.Data = []uint{1, 1}
+	objects := tmx.NewObjectLayer("object", 2, tmx.TopDown)
+	far := tmx.NewObject(1, 1, 2, 2)
+	far.X, far.Y = 1000, 1000
+	ts := tmx.NewTileSet("ground", 1, tmx.Grid{})
+
+	testcases := []struct {
+		name    string
+		tm      tmx.Map
+		wantErr error
+	}{
+		{
+			name:    "no tile set",
+			tm:      tmx.Map{Width: 2, Height: 1, Layers: []tmx.Layer{ground}},
+			wantErr: ErrInvalidTiledMap,
+		},
+		{
+			name:    "size mismatch",
+			tm:      tmx.Map{Width: 1, Height: 2, Layers: []tmx.Layer{ground}, TileSets: []tmx.TileSet{ts}},
+			wantErr: ErrInvalidTiledMap,
+		},
+		{
+			name:    "object out of map",
+			tm:      tmx.Map{Width: 2, Height: 1, Layers: []tmx.Layer{ground, {Name: "object", Type: tmx.ObjectGroup, Objects: []tmx.Object{far}}}, TileSets: []tmx.TileSet{ts}},
+			wantErr: ErrInvalidTiledMap,
+		},
+		{
+			name:    "graphic not found",
+			tm:      tmx.Map{Width: 2, Height: 1, Layers: []tmx.Layer{{Name: "object", Type: tmx.ObjectGroup, Objects: []tmx.Object{tmx.NewObject(9, 1, 2, 2)}}}, TileSets: []tmx.TileSet{ts}},
+			wantErr: ErrGraphicNotFound,
+		},
+		{
+			name:    "infinite",
+			tm:      tmx.Map{Infinite: true, Layers: []tmx.Layer{objects}},
+			wantErr: ErrInvalidTiledMap,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := MakeMapFromTiled(tc.tm, gr.MDx); !errors.Is(err, tc.wantErr) {
+				t.Errorf("MakeMapFromTiled() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMetaFromProperties(t *testing.T) {
+	testcases := []struct {
+		name  string
+		props []tmx.Property
+		want  TileMeta
+	}{
+		{name: "raw", props: []tmx.Property{{Name: "raw", Value: float64(0x13)}}, want: 0x13},
+		{
+			name: "bits",
+			props: []tmx.Property{
+				{Name: "bit0", Value: true},
+				{Name: "bit15", Value: true},
+				{Name: "bit16", Value: true},
+			},
+			want: 0x8001,
+		},
+		{
+			name: "edited",
+			props: []tmx.Property{
+				{Name: "raw", Value: float64(0x25)},
+				{Name: "bit0", Value: false},
+				{Name: "bit2", Value: true},
+				{Name: "bit5", Value: false},
+				{Name: "bit8", Value: true},
+			},
+			want: 0x104,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := metaFromProperties(tc.props); got != tc.want {
+				t.Errorf("metaFromProperties() = %#x, want %#x", got, tc.want)
+			}
+		})
+	}
+}
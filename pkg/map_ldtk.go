@@ -0,0 +1,209 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"xgtool/internal/ldtk"
+	"xgtool/internal/tmx"
+)
+
+// LDtkProject converts the Map to an LDtk project of one level, named name. The tile sets are reused from tiled, the
+// tmx.Map converted from the Map by TiledMapAtlas, so the project file should be saved in the same directory as its
+// atlas images.
+//
+// LDtk has no isometric view, so the level is in the cells of tiled (rotated -90 degrees), and each cell is TileHeight
+// pixels, where the tile objects of Tiled have the same positions. The layers are:
+//   - "Collision": IntGrid of Walkability, the value is 1 where the cell is blocked.
+//   - "Meta_bit<n>": IntGrid for each bit n set in Meta, the value is 1 where the bit is set.
+//   - "Object": Entities, one definition for each MapID, which shows its graphic and has the field "MapID".
+//   - "Ground": Tiles of the grid atlas "ground.png", the tiles are aligned to the bottom left of cells.
+func (m Map) LDtkProject(tiled tmx.Map, index GraphicIndex, name string) (proj ldtk.Project, err error) {
+	var ground, object tmx.TileSet
+	if ground, err = atlasTileSet(tiled, "ground"); err != nil {
+		return
+	}
+	if object, err = atlasTileSet(tiled, "object"); err != nil {
+		return
+	}
+
+	// reverse width and height, because the map will be rotated -90 degrees
+	pxWid, pxHei := int(m.Header.Height)*TileHeight, int(m.Header.Width)*TileHeight
+	proj = ldtk.NewProject(TileHeight, pxWid, pxHei)
+	groundUID, objectUID := newLDtkTileset(&proj, ground), newLDtkTileset(&proj, object)
+
+	level := ldtk.NewLevel(proj.UID(), ldtk.Identifier("Map_", name), pxWid, pxHei)
+	collisionDef, collision := m.ldtkCollision(&proj, level, index)
+	metaDefs, metas := m.ldtkMeta(&proj, level)
+	objectDef, objects := m.ldtkObject(&proj, level, object, objectUID, index)
+	groundDef, grounds := m.ldtkGround(&proj, level, ground, groundUID)
+
+	// the first layer is on top
+	proj.Defs.Layers = append(append(append(proj.Defs.Layers, collisionDef), metaDefs...), objectDef, groundDef)
+	level.LayerInstances = append(append(append(level.LayerInstances, collision), metas...), objects, grounds)
+	proj.Levels = append(proj.Levels, level)
+
+	return
+}
+
+// atlasTileSet returns the tile set named name of tiled from TiledMapAtlas.
+func atlasTileSet(tiled tmx.Map, name string) (ts tmx.TileSet, err error) {
+	for _, ts = range tiled.TileSets {
+		if ts.Name == name {
+			return
+		}
+	}
+
+	return ts, fmt.Errorf("%w: no tile set %s", ErrInvalidTiledMap, name)
+}
+
+// newLDtkTileset appends the tile set definition of the atlas from TiledMapAtlas, and returns its UID, or nil if the
+// atlas is empty. The grid atlas is split by its cells, the tiles of others are referenced by their sub-rectangles, so
+// the grid is only used by the editor.
+func newLDtkTileset(proj *ldtk.Project, ts tmx.TileSet) *int {
+	uid := proj.UID()
+	if len(ts.Tiles) == 0 {
+		return nil
+	}
+
+	def := ldtk.NewTilesetDef(uid, ldtk.Identifier("Tileset_", ts.Name), ts.Image, ts.ImageWidth, ts.ImageHeight, ts.TileWidth, ts.Spacing)
+	if ts.Columns == 0 {
+		def = ldtk.NewTilesetDef(uid, ldtk.Identifier("Tileset_", ts.Name), ts.Tiles[0].Image, ts.Tiles[0].ImageWidth, ts.Tiles[0].ImageHeight, TileHeight, 0)
+	}
+	proj.Defs.Tilesets = append(proj.Defs.Tilesets, def)
+
+	return &uid
+}
+
+func ldtkRect(uid int, t tmx.Tile) *ldtk.TilesetRect {
+	return &ldtk.TilesetRect{TilesetUID: uid, X: t.X, Y: t.Y, W: t.Width, H: t.Height}
+}
+
+// ldtkCell returns the index of cell i of the Map in the IntGrid of level, which is rotated -90 degrees.
+func (m Map) ldtkCell(i int) int {
+	w, h := int(m.Header.Width), int(m.Header.Height)
+	col, row := i%w, i/w
+
+	return (w-1-col)*h + row
+}
+
+// ldtkCollision creates an IntGrid layer of the blocked cells of Walkability.
+func (m Map) ldtkCollision(proj *ldtk.Project, level ldtk.Level, index GraphicIndex) (def ldtk.LayerDef, layer ldtk.LayerInstance) {
+	def = ldtk.NewLayerDef(proj.UID(), "Collision", ldtk.IntGrid, TileHeight)
+	def.DisplayOpacity = 0.5
+	def.IntGridValues = []ldtk.IntGridValueDef{{Value: 1, Identifier: "blocked", Color: "#FF0000"}}
+
+	layer = ldtk.NewLayerInstance(def, level)
+	layer.Opacity = def.DisplayOpacity
+	layer.IntGridCSV = make([]int, layer.CWid*layer.CHei)
+
+	wk := m.Walkability(index)
+	for i := range m.Ground {
+		if !wk.Walkable(i%wk.W, i/wk.W) {
+			layer.IntGridCSV[m.ldtkCell(i)] = 1
+		}
+	}
+
+	return
+}
+
+// ldtkMeta creates an IntGrid layer for each bit set in Meta, because a cell of IntGrid has only one value.
+func (m Map) ldtkMeta(proj *ldtk.Project, level ldtk.Level) (defs []ldtk.LayerDef, layers []ldtk.LayerInstance) {
+	var used TileMeta
+	for _, v := range m.Meta {
+		used |= TileMeta(v)
+	}
+
+	for _, bit := range used.Bits() {
+		def := ldtk.NewLayerDef(proj.UID(), fmt.Sprintf("Meta_%s", metaBit(bit)), ldtk.IntGrid, TileHeight)
+		def.DisplayOpacity = 0.5
+		def.IntGridValues = []ldtk.IntGridValueDef{
+			// spread the colors of bits by Knuth's multiplicative hash
+			{Value: 1, Identifier: metaBit(bit), Color: fmt.Sprintf("#%06X", uint32(bit+1)*2654435761&0xffffff)},
+		}
+
+		layer := ldtk.NewLayerInstance(def, level)
+		layer.Opacity = def.DisplayOpacity
+		layer.IntGridCSV = make([]int, layer.CWid*layer.CHei)
+		for i, v := range m.Meta {
+			if TileMeta(v).Has(bit) {
+				layer.IntGridCSV[m.ldtkCell(i)] = 1
+			}
+		}
+
+		defs, layers = append(defs, def), append(layers, layer)
+	}
+
+	return
+}
+
+// ldtkObject creates the entities at the positions of tile objects from objectCoordinate, the pivot is bottom center
+// like the tile objects in isometric map of Tiled.
+func (m Map) ldtkObject(proj *ldtk.Project, level ldtk.Level, ts tmx.TileSet, uid *int, index GraphicIndex) (def ldtk.LayerDef, layer ldtk.LayerInstance) {
+	def = ldtk.NewLayerDef(proj.UID(), "Object", ldtk.Entities, TileHeight)
+	layer = ldtk.NewLayerInstance(def, level)
+
+	entities := make(map[uint16]ldtk.EntityDef)
+	for _, t := range ts.Tiles {
+		id := t.ID + 1
+		ed := ldtk.NewEntityDef(proj.UID(), fmt.Sprintf("Object_%d", id), t.Width, t.Height)
+		ed.PivotX, ed.PivotY = 0.5, 1
+		ed.RenderMode = "Tile"
+		ed.TilesetID, ed.TileRect = uid, ldtkRect(*uid, t)
+		ed.FieldDefs = append(ed.FieldDefs, ldtk.NewIntFieldDef(proj.UID(), "MapID", id))
+
+		entities[uint16(id)] = ed
+		proj.Defs.Entities = append(proj.Defs.Entities, ed)
+	}
+
+	for i, t := range m.Object {
+		ed, ok := entities[t]
+		if !ok {
+			continue
+		}
+
+		gi := index.First(int32(t)).Info
+		x, y := objectCoordinate(int32(i), m.Header.Width, gi.Width, gi.Height, gi.OffX, gi.OffY)
+		e := ldtk.NewEntityInstance(ed, layer, level, int(math.Round(x)), int(math.Round(y)))
+		e.FieldInstances = append(e.FieldInstances, ldtk.NewIntFieldInstance(ed.FieldDefs[0], int(t)))
+
+		layer.EntityInstances = append(layer.EntityInstances, e)
+	}
+
+	return
+}
+
+// ldtkGround creates the tiles of the grid atlas ts, the tiles are larger than the cells, so they are pivoted at the
+// bottom left like the tile layer of Tiled.
+func (m Map) ldtkGround(proj *ldtk.Project, level ldtk.Level, ts tmx.TileSet, uid *int) (def ldtk.LayerDef, layer ldtk.LayerInstance) {
+	def = ldtk.NewLayerDef(proj.UID(), "Ground", ldtk.Tiles, TileHeight)
+	def.TilesetDefUID = uid
+	def.TilePivotY = 1
+
+	layer = ldtk.NewLayerInstance(def, level)
+	if uid == nil {
+		return
+	}
+	layer.TilesetRelPath = &ts.Image
+
+	tiles := make(map[uint16]int)
+	for _, t := range ts.Tiles {
+		tiles[uint16(tileMapID(t))] = t.ID
+	}
+	for i, t := range m.Ground {
+		id, ok := tiles[t]
+		if !ok {
+			continue
+		}
+
+		cell := m.ldtkCell(i)
+		layer.GridTiles = append(layer.GridTiles, ldtk.TileInstance{
+			Px:  [2]int{cell % layer.CWid * TileHeight, cell / layer.CWid * TileHeight},
+			Src: [2]int{id % ts.Columns * (ts.TileWidth + ts.Spacing), id / ts.Columns * (ts.TileHeight + ts.Spacing)},
+			T:   id,
+			D:   []int{cell},
+			A:   1,
+		})
+	}
+
+	return
+}
@@ -0,0 +1,157 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"xgtool/internal/ldtk"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMap_LDtkProject(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 5, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// grounds 1 and 2 are walkable, and object 5 blocks its cell
+	gr.MDx[1][0].Info.Access, gr.MDx[2][0].Info.Access = 1, 1
+
+	newMap := func() Map {
+		m := NewMap(4, 3)
+		m.Ground = []uint16{1, 2, 3, 4, 4, 3, 2, 1, 1, 1, 2, 9}
+		m.Object = []uint16{0, 3, 0, 0, 0, 0, 0, 5, 2, 0, 0, 0}
+		m.Meta = []uint16{0, 1, 0, 0, 0, 0, 0x12, 0, 4, 0, 0, 0xc}
+		return m
+	}
+
+	outdir := t.TempDir()
+	atlas, err := newMap().TiledMapAtlas(gr.MDx, bytes.NewReader(gf), palette, outdir, CollisionImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj, err := newMap().LDtkProject(atlas, gr.MDx, "1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, err := newMap().TiledMap(gr.MDx, bytes.NewReader(gf), palette, t.TempDir(), CollisionImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(proj.Levels) != 1 {
+		t.Fatalf("len(Levels) = %d, want 1", len(proj.Levels))
+	}
+	level := proj.Levels[0]
+	if level.Identifier != "Map_1000" || level.PxWid != 3*TileHeight || level.PxHei != 4*TileHeight {
+		t.Errorf("level = %s %dx%d, want Map_1000 %dx%d", level.Identifier, level.PxWid, level.PxHei, 3*TileHeight, 4*TileHeight)
+	}
+
+	layers := make(map[string]ldtk.LayerInstance)
+	var names []string
+	for i, l := range level.LayerInstances {
+		layers[l.Identifier] = l
+		names = append(names, l.Identifier)
+		if l.LayerDefUID != proj.Defs.Layers[i].UID {
+			t.Errorf("layer %s: LayerDefUID = %d, want %d", l.Identifier, l.LayerDefUID, proj.Defs.Layers[i].UID)
+		}
+	}
+	expectedNames := []string{"Collision", "Meta_bit0", "Meta_bit1", "Meta_bit2", "Meta_bit3", "Meta_bit4", "Object", "Ground"}
+	if diff := cmp.Diff(expectedNames, names); diff != "" {
+		t.Errorf("layers mismatch (-want +got):\n%s", diff)
+	}
+
+	// the ground is in the cells of tiled map, where GID is MapID, and refers to the cells of grid atlas
+	groundTiles, groundDef := atlas.TileSets[0], proj.Defs.Layers[len(proj.Defs.Layers)-1]
+	if groundDef.LayerType != ldtk.Tiles || groundDef.TilesetDefUID == nil || *groundDef.TilesetDefUID != proj.Defs.Tilesets[0].UID || groundDef.TilePivotY != 1 {
+		t.Errorf("ground: type = %s, tileset = %v, pivot Y = %v", groundDef.LayerType, groundDef.TilesetDefUID, groundDef.TilePivotY)
+	}
+	if def := proj.Defs.Tilesets[0]; def.RelPath != "ground.png" || def.TileGridSize != groundTiles.TileWidth || def.Spacing != atlasSpacing || def.CWid != groundTiles.Columns {
+		t.Errorf("ground tile set: %s, grid = %d, spacing = %d, columns = %d", def.RelPath, def.TileGridSize, def.Spacing, def.CWid)
+	}
+	ground := make([]uint, 12)
+	for _, tile := range layers["Ground"].GridTiles {
+		cell := tile.D[0]
+		if tile.Px != [2]int{cell % 3 * TileHeight, cell / 3 * TileHeight} {
+			t.Errorf("ground cell %d: px = %v", cell, tile.Px)
+		}
+		cw := groundTiles.TileWidth + groundTiles.Spacing
+		if tile.Src != [2]int{tile.T % groundTiles.Columns * cw, tile.T / groundTiles.Columns * cw} {
+			t.Errorf("ground cell %d: src = %v of tile %d", cell, tile.Src, tile.T)
+		}
+		ground[cell] = uint(tileMapID(groundTiles.Tiles[tile.T]))
+	}
+	if diff := cmp.Diff([]uint(tm.Layers[0].Data), ground); diff != "" {
+		t.Errorf("ground mismatch (-want +got):\n%s", diff)
+	}
+
+	// the entities are at the positions of tile objects
+	objects, entities := tm.Layers[1].Objects, layers["Object"].EntityInstances
+	if len(entities) != len(objects) {
+		t.Fatalf("len(entities) = %d, want %d", len(entities), len(objects))
+	}
+	for i, e := range entities {
+		o := objects[i]
+		id := o.GID - tm.TileSets[1].FirstGID + 1
+		want := [2]int{int(math.Round(o.X)), int(math.Round(o.Y))}
+		if e.Px != want || e.Width != int(o.Width) || e.Height != int(o.Height) || e.FieldInstances[0].Value != id {
+			t.Errorf("entity %d: px = %v, size = %dx%d, MapID = %v, want %v, %vx%v, %d", i, e.Px, e.Width, e.Height, e.FieldInstances[0].Value, want, o.Width, o.Height, id)
+		}
+	}
+
+	// (col, row) of map is (row, width-1-col) of level, the blocked cells of Walkability are 1
+	wk, collision := newMap().Walkability(gr.MDx), make([]int, 12)
+	for i, walkable := range wk.Bools() {
+		if !walkable {
+			collision[(4-1-i%4)*3+i/4] = 1
+		}
+	}
+	if diff := cmp.Diff(collision, layers["Collision"].IntGridCSV); diff != "" {
+		t.Errorf("collision mismatch (-want +got):\n%s", diff)
+	}
+
+	// each bit of Meta has its layer
+	for bit, cells := range map[int][][2]int{0: {{1, 0}}, 1: {{2, 1}}, 2: {{0, 2}, {3, 2}}, 3: {{3, 2}}, 4: {{2, 1}}} {
+		meta := make([]int, 12)
+		for _, cell := range cells {
+			meta[(4-1-cell[0])*3+cell[1]] = 1
+		}
+		if diff := cmp.Diff(meta, layers[fmt.Sprintf("Meta_bit%d", bit)].IntGridCSV); diff != "" {
+			t.Errorf("bit %d mismatch (-want +got):\n%s", bit, diff)
+		}
+	}
+
+	uids := []int{level.UID}
+	for _, l := range proj.Defs.Layers {
+		uids = append(uids, l.UID)
+	}
+	for _, e := range proj.Defs.Entities {
+		uids = append(uids, e.UID, e.FieldDefs[0].UID)
+	}
+	for _, ts := range proj.Defs.Tilesets {
+		uids = append(uids, ts.UID)
+		if _, err = os.Stat(filepath.Join(outdir, ts.RelPath)); err != nil {
+			t.Errorf("tile set %s: %v", ts.Identifier, err)
+		}
+	}
+	seen := make(map[int]bool)
+	for _, uid := range uids {
+		if seen[uid] || uid >= proj.NextUID {
+			t.Errorf("UID %d is duplicated or not less than NextUID %d", uid, proj.NextUID)
+		}
+		seen[uid] = true
+	}
+
+	if _, err = json.Marshal(proj); err != nil {
+		t.Fatal(err)
+	}
+}
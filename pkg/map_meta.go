@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"fmt"
+	"xgtool/internal/tmx"
+)
+
+// TileMeta is the attribute of a cell, from the third block (Map.Meta) of map file.
+//
+// The meanings of the bits are undocumented, so they are only exposed as raw bits.
+type TileMeta uint16
+
+// Has reports whether bit (0 to 15) is set.
+func (t TileMeta) Has(bit int) bool { return bit >= 0 && bit < 16 && t&(1<<bit) != 0 }
+
+// Bits returns the positions of set bits in ascending order, nil if no bit is set.
+func (t TileMeta) Bits() (bits []int) {
+	for i := 0; i < 16; i++ {
+		if t.Has(i) {
+			bits = append(bits, i)
+		}
+	}
+
+	return
+}
+
+// MetaAt returns the attribute of cell (col, row), it's 0 if the cell is out of map.
+func (m Map) MetaAt(col, row int) TileMeta {
+	w, h := int(m.Header.Width), int(m.Header.Height)
+	if col < 0 || col >= w || row < 0 || row >= h || row*w+col >= len(m.Meta) {
+		return 0
+	}
+
+	return TileMeta(m.Meta[row*w+col])
+}
+
+// buildMetaLayer creates a hidden object layer, which has a rectangle for each cell with non-zero Meta. The rectangle
+// has the attribute as "raw", and a bool "bit<n>" for each set bit. Object IDs start from id.
+func (m Map) buildMetaLayer(id int) (layer tmx.Layer) {
+	layer = tmx.NewObjectLayer("meta", 3, tmx.TopDown)
+	layer.Visible = false
+
+	w := int(m.Header.Width)
+	for i, v := range m.Meta {
+		if v == 0 {
+			continue
+		}
+
+		// the cell (col, row) is (row, w-1-col) in tiled map, because the map is rotated -90 degrees
+		col, row := i%w, i/w
+
+		obj := tmx.NewObject(0, id, TileHeight, TileHeight)
+		obj.Name = "meta"
+		obj.Type = "meta"
+		obj.X, obj.Y = float64(row*TileHeight), float64((w-1-col)*TileHeight)
+		obj.Properties = []tmx.Property{{Name: "raw", Type: "int", Value: int(v)}}
+		for _, b := range TileMeta(v).Bits() {
+			obj.Properties = append(obj.Properties, tmx.Property{Name: metaBit(b), Type: "bool", Value: true})
+		}
+
+		layer.Objects = append(layer.Objects, obj)
+		id++
+	}
+
+	return
+}
+
+// metaFromProperties returns the attribute from the properties of buildMetaLayer. It starts from raw, and then the
+// bool "bit<n>" sets or clears bit n, so the bits edited in Tiled are kept.
+func metaFromProperties(props []tmx.Property) (t TileMeta) {
+	for _, p := range props {
+		if v, ok := intValue(p.Value); ok && p.Name == "raw" {
+			t = TileMeta(v)
+		}
+	}
+	for _, p := range props {
+		b, ok := p.Value.(bool)
+		if !ok {
+			continue
+		}
+
+		for bit := 0; bit < 16; bit++ {
+			if p.Name != metaBit(bit) {
+				continue
+			}
+
+			if b {
+				t |= 1 << bit
+			} else {
+				t &^= 1 << bit
+			}
+		}
+	}
+
+	return
+}
+
+func metaBit(bit int) string {
+	return fmt.Sprintf("bit%d", bit)
+}
+
+// intValue returns the value of int property, it's float64 if decoded from JSON.
+func intValue(v any) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"slices"
+	"testing"
+	"xgtool/internal/tmx"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTileMeta(t *testing.T) {
+	testcases := []struct {
+		meta TileMeta
+		bits []int
+	}{
+		{0, nil},
+		{0x1, []int{0}},
+		{0x12, []int{1, 4}},
+		{0xc001, []int{0, 14, 15}},
+	}
+
+	for _, tc := range testcases {
+		if diff := cmp.Diff(tc.bits, tc.meta.Bits()); diff != "" {
+			t.Errorf("%#04x: Bits() mismatch (-want +got):\n%s", uint16(tc.meta), diff)
+		}
+		for bit := -1; bit <= 16; bit++ {
+			if got, want := tc.meta.Has(bit), slices.Contains(tc.bits, bit); got != want {
+				t.Errorf("%#04x: Has(%d) = %v, want %v", uint16(tc.meta), bit, got, want)
+			}
+		}
+	}
+}
+
+func TestMap_MetaAt(t *testing.T) {
+	m := Map{Header: mapHeader{Width: 3, Height: 2}, Meta: []uint16{0, 1, 2, 3, 4, 5}}
+
+	testcases := []struct {
+		col, row int
+		expected TileMeta
+	}{
+		{0, 0, 0},
+		{2, 0, 2},
+		{1, 1, 4},
+		{3, 0, 0},
+		{0, 2, 0},
+		{-1, 0, 0},
+	}
+
+	for _, tc := range testcases {
+		if got := m.MetaAt(tc.col, tc.row); got != tc.expected {
+			t.Errorf("MetaAt(%d, %d): expected %d, got %d", tc.col, tc.row, tc.expected, got)
+		}
+	}
+}
+
+func TestMap_buildMetaLayer(t *testing.T) {
+	m := Map{Header: mapHeader{Width: 2, Height: 2}, Meta: []uint16{0, 0x4, 0, 0x101}}
+
+	layer := m.buildMetaLayer(10)
+	if layer.Visible {
+		t.Error("expected hidden layer")
+	}
+
+	expected := []tmx.Object{
+		{
+			ID: 10, Name: "meta", Type: "meta", X: 0, Y: 0, Width: TileHeight, Height: TileHeight, Visible: true,
+			Properties: []tmx.Property{
+				{Name: "raw", Type: "int", Value: 4},
+				{Name: "bit2", Type: "bool", Value: true},
+			},
+		},
+		{
+			ID: 11, Name: "meta", Type: "meta", X: TileHeight, Y: 0, Width: TileHeight, Height: TileHeight, Visible: true,
+			Properties: []tmx.Property{
+				{Name: "raw", Type: "int", Value: 0x101},
+				{Name: "bit0", Type: "bool", Value: true},
+				{Name: "bit8", Type: "bool", Value: true},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, layer.Objects); diff != "" {
+		t.Errorf("buildMetaLayer() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,189 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"sort"
+)
+
+// ErrEmptyCrop is returned when the crop rectangle has no cells of the map.
+var ErrEmptyCrop = errors.New("empty crop")
+
+// MapRenderer composites the ground and object layers of a Map into one isometric image.
+type MapRenderer struct {
+	Index       GraphicIndex // graphics indexed by MapID, such as GraphicResource.MDx
+	GraphicFile io.ReaderAt
+	Palette     color.Palette
+	Scale       float64         // scale of output image, 0 means 1
+	Background  color.Color     // background color, nil means transparent
+	Crop        image.Rectangle // renders the tiles in this rectangle of cells only (X is column, Y is row), empty means all
+}
+
+// MapImage is a rendered map, Origin is the position of the center of cell (0, 0) before scaling.
+type MapImage struct {
+	*image.RGBA
+	Origin image.Point
+	Scale  float64
+}
+
+// Project returns the position of the center of cell (col, row) in image.
+func (mi MapImage) Project(col, row int) image.Point {
+	p := CellCenter(col, row).Add(mi.Origin)
+
+	return image.Pt(int(float64(p.X)*mi.Scale), int(float64(p.Y)*mi.Scale))
+}
+
+// CellCenter returns the position of the center of cell (col, row), relative to the center of cell (0, 0).
+//
+// It's the same projection as the tmx.Map from Map.TiledMap, columns go to the upper right and rows go to the lower
+// right.
+func CellCenter(col, row int) image.Point {
+	return image.Pt(
+		(row+col)*TileWidth/2,
+		int(math.Floor(float64(row-col)*TileHeight/2)),
+	)
+}
+
+// mapSprite is a graphic drawn on map, bounds is relative to the center of cell (0, 0).
+type mapSprite struct {
+	id     uint16
+	bounds image.Rectangle
+	anchor image.Point // center of cell, for depth sorting
+}
+
+// Render renders m, the ground layer is drawn first, and then the object layer sorted by depth.
+//
+// Every graphic is drawn at the center of its cell plus the graphic offset, like the client does. Tiles not found in
+// index are skipped.
+func (r MapRenderer) Render(m Map) (mi MapImage, err error) {
+	w, h := int(m.Header.Width), int(m.Header.Height)
+	crop := image.Rect(0, 0, w, h)
+	if !r.Crop.Empty() {
+		crop = crop.Intersect(r.Crop)
+	}
+	if crop.Empty() {
+		return mi, fmt.Errorf("%w: crop=%v, map=%dx%d", ErrEmptyCrop, r.Crop, w, h)
+	}
+	mi.Scale = r.Scale
+	if mi.Scale <= 0 {
+		mi.Scale = 1
+	}
+
+	var bounds image.Rectangle
+	ground := r.sprites(m.Ground, w, crop, &bounds)
+	objects := r.sprites(m.Object, w, crop, &bounds)
+	sort.SliceStable(objects, func(i, j int) bool {
+		if objects[i].anchor.Y != objects[j].anchor.Y {
+			return objects[i].anchor.Y < objects[j].anchor.Y
+		}
+
+		return objects[i].anchor.X < objects[j].anchor.X
+	})
+
+	// the cells are included, so an empty map is still rendered
+	for _, c := range []image.Point{crop.Min, image.Pt(crop.Max.X-1, crop.Min.Y), image.Pt(crop.Min.X, crop.Max.Y-1), crop.Max.Sub(image.Pt(1, 1))} {
+		center := CellCenter(c.X, c.Y)
+		bounds = bounds.Union(image.Rect(center.X-TileWidth/2, center.Y-TileHeight/2, center.X+TileWidth/2, center.Y+TileHeight/2))
+	}
+
+	mi.Origin = bounds.Min.Mul(-1)
+	mi.RGBA = image.NewRGBA(image.Rect(0, 0, scaled(bounds.Dx(), mi.Scale), scaled(bounds.Dy(), mi.Scale)))
+	if r.Background != nil {
+		draw.Draw(mi.RGBA, mi.Rect, image.NewUniform(r.Background), image.Point{}, draw.Src)
+	}
+
+	// sprites are drawn into the scaled canvas one by one, the decoded ones are kept until the cache is full
+	cache, cached := make(map[uint16]*image.RGBA), 0
+	for _, s := range append(ground, objects...) {
+		img, ok := cache[s.id]
+		if !ok {
+			if img, err = r.image(s.id); err != nil {
+				return
+			}
+			if cached += len(img.Pix); cached <= maxCachedSprites {
+				cache[s.id] = img
+			}
+		}
+
+		drawScaled(mi.RGBA, s.bounds.Min.Add(mi.Origin), img, mi.Scale)
+	}
+
+	return
+}
+
+// sprites returns the sprites of tiles in crop, and extends bounds to include them.
+func (r MapRenderer) sprites(tiles []uint16, w int, crop image.Rectangle, bounds *image.Rectangle) (sprites []mapSprite) {
+	for i, t := range tiles {
+		col, row := i%w, i/w
+		if t == 0 || !image.Pt(col, row).In(crop) {
+			continue
+		}
+
+		g := r.Index.First(int32(t))
+		if g == nil {
+			continue
+		}
+
+		anchor := CellCenter(col, row)
+		at := anchor.Add(image.Pt(int(g.Info.OffX), int(g.Info.OffY)))
+		s := mapSprite{
+			id:     t,
+			bounds: image.Rect(at.X, at.Y, at.X+int(g.Info.Width), at.Y+int(g.Info.Height)),
+			anchor: anchor,
+		}
+
+		*bounds = bounds.Union(s.bounds)
+		sprites = append(sprites, s)
+	}
+
+	return
+}
+
+func (r MapRenderer) image(id uint16) (img *image.RGBA, err error) {
+	var g *Graphic
+	if g, err = r.Index.First(int32(id)).Info.LoadGraphicAt(r.GraphicFile); err != nil {
+		return nil, fmt.Errorf("map id=%d: %w", id, err)
+	}
+
+	return g.ImgRGBA(r.Palette)
+}
+
+// maxCachedSprites is the maximum bytes of decoded sprites kept by MapRenderer.Render.
+const maxCachedSprites = 64 << 20
+
+func scaled(n int, s float64) int {
+	return int(math.Ceil(float64(n) * s))
+}
+
+// drawScaled draws img at (the unscaled) at over dst with nearest neighbor, as if img is drawn on a canvas of scale 1
+// and then the canvas is resized by factor s.
+func drawScaled(dst *image.RGBA, at image.Point, img *image.RGBA, s float64) {
+	if s == 1 {
+		draw.Draw(dst, img.Rect.Sub(img.Rect.Min).Add(at), img, img.Rect.Min, draw.Over)
+		return
+	}
+
+	b := img.Bounds()
+	r := image.Rect(scaled(at.X, s), scaled(at.Y, s), scaled(at.X+b.Dx(), s), scaled(at.Y+b.Dy(), s)).Intersect(dst.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		sy := max(min(int(float64(y)/s)-at.Y, b.Dy()-1), 0)
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sx := max(min(int(float64(x)/s)-at.X, b.Dx()-1), 0)
+			src := img.Pix[img.PixOffset(b.Min.X+sx, b.Min.Y+sy):]
+			out := dst.Pix[dst.PixOffset(x, y):]
+			if a := uint32(src[3]); a == 0xff {
+				copy(out[:4], src[:4])
+			} else if a > 0 {
+				// src is premultiplied, so it's src + dst * (1 - src alpha)
+				for i := 0; i < 4; i++ {
+					out[i] = byte(uint32(src[i]) + uint32(out[i])*(0xff-a)/0xff)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestCellCenter(t *testing.T) {
+	testcases := []struct {
+		col, row int
+		expected image.Point
+	}{
+		{0, 0, image.Pt(0, 0)},
+		{1, 0, image.Pt(32, -24)},
+		{0, 1, image.Pt(32, 23)},
+		{1, 1, image.Pt(64, 0)},
+		{3, 1, image.Pt(128, -47)},
+	}
+
+	for _, tc := range testcases {
+		if got := CellCenter(tc.col, tc.row); got != tc.expected {
+			t.Errorf("CellCenter(%d, %d): expected %v, got %v", tc.col, tc.row, tc.expected, got)
+		}
+	}
+}
+
+// TestCellCenter_ObjectCoordinate checks the projection of CellCenter agrees with objectCoordinate, the bottom center
+// of a graphic drawn by MapRenderer is where Tiled draws the object from Map.TiledMap.
+func TestCellCenter_ObjectCoordinate(t *testing.T) {
+	const mapWidth = 5
+
+	// Tiled projects (x, y) of objects on an isometric map, whose height is mapWidth in tiles, to
+	// ((x - y) / TileHeight * TileWidth / 2 + mapWidth * TileWidth / 2, (x + y) / 2)
+	tiled := func(x, y float64) (float64, float64) {
+		return (x-y)/TileHeight*TileWidth/2 + mapWidth*TileWidth/2, (x + y) / 2
+	}
+
+	infos := []GraphicInfo{
+		{Width: 64, Height: 47, OffX: -32, OffY: -24},
+		{Width: 30, Height: 100, OffX: -10, OffY: -80},
+		{Width: 128, Height: 96, OffX: -64, OffY: 0},
+	}
+	for i := int32(0); i < mapWidth*3; i++ {
+		col, row := int(i%mapWidth), int(i/mapWidth)
+		for _, gi := range infos {
+			x, y := tiled(objectCoordinate(i, mapWidth, gi.Width, gi.Height, gi.OffX, gi.OffY))
+
+			// the screen of Tiled is moved by (TileWidth / 2, mapWidth * TileHeight / 2) from the renderer, and CellCenter
+			// rounds Y down
+			c := CellCenter(col, row)
+			wx := float64(c.X) + float64(gi.OffX) + float64(gi.Width)/2 + TileWidth/2
+			wy := float64(c.Y) + float64(gi.OffY) + float64(gi.Height) + float64(mapWidth*TileHeight)/2
+			if math.Abs(x-wx) > 1e-9 || math.Abs(y-wy) >= 1 {
+				t.Errorf("cell (%d, %d), %+v: expected (%v, %v), got (%v, %v)", col, row, gi, wx, wy, x, y)
+			}
+		}
+	}
+}
+
+func TestMapRenderer_Render(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 2, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// graphic 1: 2x2 at (-1, 0), graphic 2: 3x3 at (-2, -1)
+	m := Map{
+		Header: mapHeader{Width: 2, Height: 2},
+		Ground: []uint16{1, 1, 1, 1},
+		Object: []uint16{0, 0, 0, 2},
+		Meta:   []uint16{0, 0, 0, 0},
+	}
+	bg := color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff}
+	r := MapRenderer{Index: gr.MDx, GraphicFile: bytes.NewReader(gf), Palette: palette, Background: bg}
+
+	mi, err := r.Render(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cells are from (-32, -47) to (96, 46)
+	if mi.Bounds() != image.Rect(0, 0, 128, 93) {
+		t.Errorf("expected bounds: %v, got %v", image.Rect(0, 0, 128, 93), mi.Bounds())
+	}
+	if mi.Origin != image.Pt(32, 47) || mi.Project(0, 0) != image.Pt(32, 47) {
+		t.Errorf("expected origin: %v, got %v, %v", image.Pt(32, 47), mi.Origin, mi.Project(0, 0))
+	}
+
+	testcases := []struct {
+		pt       image.Point
+		expected color.Color
+	}{
+		{image.Pt(0, 0), bg},                               // background
+		{image.Pt(32, 47), palette[1]},                     // ground of cell (0, 0), at (31, 47), row is [0, 1]
+		{image.Pt(31, 47), bg},                             // the transparent pixel of ground
+		{image.Pt(95, 48), palette[1]},                     // object of cell (1, 1), at (94, 46), the bottom row is [0, 1, 0]
+		{image.Pt(94, 46), bg},                             // the transparent pixel of object
+		{mi.Project(1, 1).Add(image.Pt(0, 1)), palette[1]}, // the ground of cell (1, 1) at (95, 47)
+	}
+	for _, tc := range testcases {
+		r1, g1, b1, a1 := mi.At(tc.pt.X, tc.pt.Y).RGBA()
+		r2, g2, b2, a2 := tc.expected.RGBA()
+		if r1 != r2 || g1 != g2 || b1 != b2 || (a1 == 0) != (a2 == 0) {
+			t.Errorf("%v: expected %v, got %v", tc.pt, tc.expected, mi.At(tc.pt.X, tc.pt.Y))
+		}
+	}
+
+	// crop the cell (1, 1) only, and scale to half
+	r.Crop, r.Scale = image.Rect(1, 1, 2, 2), 0.5
+	if mi, err = r.Render(m); err != nil {
+		t.Fatal(err)
+	}
+	if mi.Bounds() != image.Rect(0, 0, 32, 23) {
+		t.Errorf("expected bounds: %v, got %v", image.Rect(0, 0, 32, 23), mi.Bounds())
+	}
+	if mi.Project(1, 1) != image.Pt(16, 11) {
+		t.Errorf("expected center of cell (1, 1): %v, got %v", image.Pt(16, 11), mi.Project(1, 1))
+	}
+
+	r.Crop = image.Rect(5, 5, 6, 6)
+	if _, err = r.Render(m); !errors.Is(err, ErrEmptyCrop) {
+		t.Errorf("expected error: %v, got %v", ErrEmptyCrop, err)
+	}
+}
@@ -1,9 +1,18 @@
 package pkg
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"image/color"
 	"os"
+	"path/filepath"
 	"testing"
+	"xgtool/internal/tmx"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestMakeMap(t *testing.T) {
@@ -31,6 +40,34 @@ func TestMakeMap(t *testing.T) {
 			}
 		})
 	}
+
+	// every byte of magic is checked
+	testcases := []struct {
+		magic string
+		err   error
+	}{
+		{"MAP", nil},
+		{"MAX", ErrInvalidMagic},
+		{"XAP", ErrInvalidMagic},
+		{"RD", ErrInvalidMagic},
+	}
+
+	for _, tc := range testcases {
+		t.Run("magic "+tc.magic, func(t *testing.T) {
+			m := NewMap(2, 1)
+			m.Header.Magic = [12]byte{}
+			copy(m.Header.Magic[:], tc.magic)
+
+			buf := new(bytes.Buffer)
+			if err := m.Write(buf); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := MakeMap(buf); !errors.Is(err, tc.err) {
+				t.Errorf("MakeMap() error = %v, want %v", err, tc.err)
+			}
+		})
+	}
 }
 
 func TestMap_TiledMap(t *testing.T) {
@@ -44,7 +81,7 @@ func TestMap_TiledMap(t *testing.T) {
 	err = res.OpenMap("../testdata/map/1091.dat")
 	skipIfNotExists("../testdata/map/1091.dat", err, t)
 
-	tm, err := res.Map.TiledMap(res.GraphicResource.MDx, res.GraphicFile, res.Palette, "../output/")
+	tm, err := res.Map.TiledMap(res.GraphicResource.MDx, res.GraphicFile, res.Palette, "../output/", CollisionImage)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -54,3 +91,109 @@ func TestMap_TiledMap(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestMap_TiledMap_GIDRanges checks the GID ranges of tile sets don't overlap, the range of an image collection tile
+// set is from FirstGID to FirstGID plus the largest tile ID.
+func TestMap_TiledMap_GIDRanges(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 4, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the largest MapIDs are not in the first cells
+	m := Map{
+		Header: mapHeader{Width: 2, Height: 2},
+		Ground: []uint16{1, 3, 1, 1},
+		Object: []uint16{0, 2, 4, 0},
+		Meta:   []uint16{0, 0, 0, 0},
+	}
+	outdir := t.TempDir()
+	tm, err := m.TiledMap(gr.MDx, bytes.NewReader(gf), palette, outdir, CollisionImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tm.TileSets) != 3 {
+		t.Fatalf("len(TileSets) = %d, want 3", len(tm.TileSets))
+	}
+	last := 0
+	for _, ts := range tm.TileSets {
+		if ts.FirstGID <= last {
+			t.Errorf("tile set %s: FirstGID = %d, overlaps the previous tile set ending at %d", ts.Name, ts.FirstGID, last)
+		}
+		last = ts.FirstGID
+		for _, tile := range ts.Tiles {
+			last = max(last, ts.FirstGID+tile.ID)
+		}
+	}
+
+	// the objects and blocked cells refer to their own tile sets
+	for _, o := range tm.Layers[1].Objects {
+		if gid := int(o.GID); gid < tm.TileSets[1].FirstGID || gid >= tm.TileSets[2].FirstGID {
+			t.Errorf("object %d: GID = %d, not in tile set object", o.ID, gid)
+		}
+	}
+	for _, gid := range tm.Layers[3].Data {
+		if gid != 0 && int(gid) != tm.TileSets[2].FirstGID {
+			t.Errorf("collision GID = %d, want %d", gid, tm.TileSets[2].FirstGID)
+		}
+	}
+
+	// the collision tile image is written by the caller once
+	if _, err = os.Stat(filepath.Join(outdir, CollisionImage)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no collision image in outdir, got %v", err)
+	}
+}
+
+func TestMap_TiledMap_XML(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 2, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := Map{
+		Header: mapHeader{Width: 3, Height: 2},
+		Ground: []uint16{1, 1, 2, 2, 1, 1},
+		Object: []uint16{0, 2, 0, 0, 0, 0},
+		Meta:   []uint16{0, 1, 0, 2, 0, 0},
+	}
+	tm, err := m.TiledMap(gr.MDx, bytes.NewReader(gf), palette, t.TempDir(), CollisionImage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(tm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON tmx.Map
+	if err = json.Unmarshal(b, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, err = xml.Marshal(tm); err != nil {
+		t.Fatal(err)
+	}
+	var fromXML tmx.Map
+	if err = xml.Unmarshal(b, &fromXML); err != nil {
+		t.Fatal(err)
+	}
+
+	// the data of tile layers is written in CSV by default
+	csv := cmp.Transformer("encoding", func(e tmx.Encoding) tmx.Encoding {
+		if e == "" {
+			return tmx.CSV
+		}
+
+		return e
+	})
+	if diff := cmp.Diff(fromJSON, fromXML, cmpopts.EquateEmpty(), csv); diff != "" {
+		t.Errorf("xml.Unmarshal() mismatch with json.Unmarshal() (-json +xml):\n%s", diff)
+	}
+}
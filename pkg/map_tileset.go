@@ -0,0 +1,289 @@
+package pkg
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"xgtool/internal/tmx"
+)
+
+// externalCollisionGID is the FirstGID of collision tile set in the map with ExternalTileSet, it follows the largest
+// MapID.
+const externalCollisionGID = math.MaxUint16 + 1
+
+// ExternalTileSet is an image collection tile set shared by maps, which is saved to its own file and referenced by
+// TileSet.Source. Its FirstGID is 1 and the tile ID is MapID - 1, so the GID is always MapID.
+//
+// The tile set file is Path, .tsx is XML and others are JSON (.tsj). The images are rendered to the directory named
+// after Path without extension, such as "tiles/GraphicInfo_66.tsj" and "tiles/GraphicInfo_66/1.png". The existing
+// images are not rendered again, so Path should be keyed on the graphic info, graphic and palette files.
+//
+// It's safe for concurrent use, if GraphicFile is.
+type ExternalTileSet struct {
+	Path        string
+	Index       GraphicIndex // graphics indexed by MapID, such as GraphicResource.MDx
+	GraphicFile io.ReaderAt
+	Palette     color.Palette
+
+	mu       sync.Mutex
+	tiles    map[uint16]tmx.Tile
+	rendered map[uint16]bool
+}
+
+// NewExternalTileSet creates an ExternalTileSet saved to path, the tiles in the existing file are kept.
+func NewExternalTileSet(path string, index GraphicIndex, gf io.ReaderAt, p color.Palette) (ets *ExternalTileSet, err error) {
+	ets = &ExternalTileSet{
+		Path:        path,
+		Index:       index,
+		GraphicFile: gf,
+		Palette:     p,
+		tiles:       make(map[uint16]tmx.Tile),
+		rendered:    make(map[uint16]bool),
+	}
+
+	var b []byte
+	if b, err = os.ReadFile(path); errors.Is(err, os.ErrNotExist) {
+		return ets, nil
+	} else if err != nil {
+		return
+	}
+
+	var ts tmx.TileSet
+	if ets.isXML() {
+		err = xml.Unmarshal(b, &ts)
+	} else {
+		err = json.Unmarshal(b, &ts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, t := range ts.Tiles {
+		ets.tiles[uint16(t.ID+1)] = t
+	}
+
+	return
+}
+
+func (ets *ExternalTileSet) isXML() bool {
+	return strings.EqualFold(filepath.Ext(ets.Path), ".tsx")
+}
+
+// name returns the name of tile set, and the directory of images relative to the tile set file.
+func (ets *ExternalTileSet) name() string {
+	return strings.TrimSuffix(filepath.Base(ets.Path), filepath.Ext(ets.Path))
+}
+
+// add adds tiles to the tile set and renders their images, the tiles not found in the index are marked as empty like
+// Map.buildTileSet. A tile is added after its image is rendered, so the failed ones are rendered again for the next
+// map.
+func (ets *ExternalTileSet) add(tiles []uint16) (err error) {
+	var infos []GraphicInfo
+
+	ets.mu.Lock()
+	queued := make(map[uint16]bool)
+	for i, t := range tiles {
+		if t == 0 {
+			continue
+		}
+		if _, ok := ets.Index[int32(t)]; !ok {
+			tiles[i] = 0
+			continue
+		}
+		if ets.rendered[t] || queued[t] {
+			continue
+		}
+
+		queued[t] = true
+		infos = append(infos, ets.Index.First(int32(t)).Info)
+	}
+	ets.mu.Unlock()
+
+	if len(infos) == 0 {
+		return
+	}
+
+	outdir := strings.TrimSuffix(ets.Path, filepath.Ext(ets.Path))
+	if err = os.MkdirAll(outdir, 0755); err != nil {
+		return
+	}
+	for _, info := range infos {
+		if err = renderAt(info, ets.GraphicFile, ets.Palette, outdir); err != nil {
+			return
+		}
+
+		t := uint16(info.MapID)
+		ets.mu.Lock()
+		ets.rendered[t] = true
+		ets.tiles[t] = tmx.Tile{
+			ID:          int(t) - 1,
+			Image:       fmt.Sprintf("%s/%d.png", ets.name(), t),
+			ImageWidth:  int(info.Width),
+			ImageHeight: int(info.Height),
+		}
+		ets.mu.Unlock()
+	}
+
+	return
+}
+
+// ref returns the reference of tile set, and the path of collision tile image next to it, in the map saved in outdir.
+func (ets *ExternalTileSet) ref(outdir string) (ts tmx.TileSet, collision string, err error) {
+	var dir, path string
+	if dir, err = filepath.Abs(outdir); err != nil {
+		return
+	}
+	if path, err = filepath.Abs(ets.Path); err != nil {
+		return
+	}
+
+	var rel string
+	if rel, err = filepath.Rel(dir, path); err != nil {
+		return
+	}
+
+	return tmx.TileSet{FirstGID: 1, Source: filepath.ToSlash(rel)}, filepath.Join(filepath.Dir(rel), CollisionImage), nil
+}
+
+// TileSet returns the tile set of the added tiles and the tiles in the existing file, sorted by ID.
+func (ets *ExternalTileSet) TileSet() (ts tmx.TileSet) {
+	ets.mu.Lock()
+	defer ets.mu.Unlock()
+
+	ts = tmx.NewTileSet(ets.name(), 0, tmx.Grid{Orientation: tmx.Orthogonal, Width: 1, Height: 1})
+	ts.TileWidth, ts.TileHeight = TileWidth, TileHeight
+	for _, t := range ets.tiles {
+		ts.Tiles = append(ts.Tiles, t)
+	}
+	sort.Slice(ts.Tiles, func(i, j int) bool {
+		return ts.Tiles[i].ID < ts.Tiles[j].ID
+	})
+	ts.TileCount = len(ts.Tiles)
+
+	return
+}
+
+// Save writes the tile set to Path, and the collision tile image to the same directory. It should be called after all
+// maps are converted.
+func (ets *ExternalTileSet) Save() (err error) {
+	ts := ets.TileSet()
+
+	var out []byte
+	if ets.isXML() {
+		if out, err = xml.MarshalIndent(ts, "", " "); err != nil {
+			return
+		}
+		out = append([]byte(xml.Header), out...)
+	} else if out, err = json.Marshal(ts); err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(ets.Path), 0755); err != nil {
+		return
+	}
+	if err = WriteCollisionTile(filepath.Join(filepath.Dir(ets.Path), CollisionImage)); err != nil {
+		return
+	}
+
+	return os.WriteFile(ets.Path, out, 0644)
+}
+
+// TiledMapExternal converts the Map to a tmx.Map like TiledMap, but the ground and object layers share ets, so the
+// GID is MapID. The tile set and the collision tile image next to it are referenced relative to outdir, where the map
+// file is saved, and ets.Save should be called after converting.
+func (m Map) TiledMapExternal(ets *ExternalTileSet, outdir string) (tiled tmx.Map, err error) {
+	tiled = tmx.NewMap(
+		// reverse width and height, because the map will be rotated -90 degrees
+		int(m.Header.Height),
+		int(m.Header.Width),
+		tmx.Isometric,
+		tmx.LeftUp,
+	)
+	tiled.Layers = make([]tmx.Layer, 0, 4)
+	tiled.TileSets = make([]tmx.TileSet, 0, 2)
+
+	if err = ets.add(m.Ground); err != nil {
+		return
+	}
+	if err = ets.add(m.Object); err != nil {
+		return
+	}
+
+	var ts tmx.TileSet
+	var collision string
+	if ts, collision, err = ets.ref(outdir); err != nil {
+		return
+	}
+	tiled.TileSets = append(tiled.TileSets, ts)
+
+	var layer tmx.Layer
+	if layer, err = m.buildGroundLayer(nil); err != nil {
+		return
+	}
+	tiled.Layers = append(tiled.Layers, layer)
+
+	if layer, err = m.buildObjectLayer(ets.Index, 0); err != nil {
+		return
+	}
+	tiled.Layers = append(tiled.Layers, layer)
+
+	m.setMeta(&tiled)
+	if err = m.setCollision(&tiled, externalCollisionGID-1, ets.Index, collision); err != nil {
+		return
+	}
+
+	tiled.TileWidth = TileWidth
+	tiled.TileHeight = TileHeight
+
+	return
+}
+
+// renderAt renders the graphic of info to outdir like render, but reads gf by io.ReaderAt, and skips the existing
+// image. The image is written to a temporary file and renamed, so a failed or concurrent rendering never leaves a
+// partial image to be skipped.
+func renderAt(info GraphicInfo, gf io.ReaderAt, p color.Palette, outdir string) (err error) {
+	name := filepath.Join(outdir, fmt.Sprintf("%d.png", info.MapID))
+	if _, err = os.Stat(name); err == nil {
+		return
+	}
+
+	var g *Graphic
+	if g, err = info.LoadGraphicAt(gf); err != nil {
+		return
+	}
+
+	var img image.Image
+	if img, err = g.ImgRGBA(p); err != nil {
+		return
+	}
+
+	var out *os.File
+	if out, err = os.CreateTemp(outdir, fmt.Sprintf("%d.*.png", info.MapID)); err != nil {
+		return
+	}
+	if err = out.Chmod(0644); err == nil {
+		err = png.Encode(out, img)
+	}
+	if err != nil {
+		_ = out.Close()
+		_ = os.Remove(out.Name())
+		return
+	}
+	if err = out.Close(); err != nil {
+		_ = os.Remove(out.Name())
+		return
+	}
+
+	return os.Rename(out.Name(), name)
+}
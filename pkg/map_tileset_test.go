@@ -0,0 +1,187 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"xgtool/internal/tmx"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/samber/lo"
+)
+
+func TestMap_TiledMapExternal(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 4, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ext := range []string{".tsj", ".tsx"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "tiles", "GraphicInfo"+ext)
+			outdir := filepath.Join(dir, "maps", "a")
+			if err := os.MkdirAll(outdir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			ets, err := NewExternalTileSet(path, gr.MDx, bytes.NewReader(gf), palette)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := NewMap(2, 2)
+			m.Ground = []uint16{1, 2, 2, 1}
+			m.Object = []uint16{0, 3, 0, 0}
+			want := NewMap(2, 2)
+			copy(want.Ground, m.Ground)
+			copy(want.Object, m.Object)
+
+			tm, err := m.TiledMapExternal(ets, outdir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantTileSets := []int{1, externalCollisionGID}
+			if diff := cmp.Diff(wantTileSets, lo.Map(tm.TileSets, func(ts tmx.TileSet, _ int) int { return ts.FirstGID })); diff != "" {
+				t.Errorf("FirstGID mismatch (-want +got):\n%s", diff)
+			}
+			if tm.TileSets[0].Source != "../../tiles/GraphicInfo"+ext {
+				t.Errorf("Source = %q", tm.TileSets[0].Source)
+			}
+			// the collision tile is next to the shared tile set, not in every map
+			if image := tm.TileSets[1].Tiles[0].Image; image != "../../tiles/"+CollisionImage {
+				t.Errorf("collision image = %q", image)
+			}
+			// GID is MapID
+			if diff := cmp.Diff(tmx.Data{2, 1, 1, 2}, tm.Layers[0].Data); diff != "" {
+				t.Errorf("ground mismatch (-want +got):\n%s", diff)
+			}
+			if gid := tm.Layers[1].Objects[0].GID; gid != 3 {
+				t.Errorf("object GID = %d, want 3", gid)
+			}
+
+			b, err := json.Marshal(tm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = json.Unmarshal(b, &tm); err != nil {
+				t.Fatal(err)
+			}
+			got, err := MakeMapFromTiled(tm, gr.MDx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got, cmp.AllowUnexported(Map{})); diff != "" {
+				t.Errorf("MakeMapFromTiled() mismatch (-want +got):\n%s", diff)
+			}
+
+			if err = ets.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = os.Stat(filepath.Join(dir, "tiles", CollisionImage)); err != nil {
+				t.Error(err)
+			}
+			if _, err = os.Stat(filepath.Join(outdir, CollisionImage)); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected no collision image in map directory, got %v", err)
+			}
+
+			// the existing image isn't rendered again
+			stale := filepath.Join(dir, "tiles", "GraphicInfo", "1.png")
+			if err = os.WriteFile(stale, []byte("stale"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// another map shares the saved tile set, 9 isn't in the index
+			if ets, err = NewExternalTileSet(path, gr.MDx, bytes.NewReader(gf), palette); err != nil {
+				t.Fatal(err)
+			}
+			m2 := NewMap(2, 1)
+			m2.Ground = []uint16{1, 9}
+			m2.Object = []uint16{4, 0}
+			if _, err = m2.TiledMapExternal(ets, outdir); err != nil {
+				t.Fatal(err)
+			}
+			if err = ets.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			if b, err = os.ReadFile(stale); err != nil || string(b) != "stale" {
+				t.Errorf("expected the existing image is skipped, got %q, %v", b, err)
+			}
+
+			if ets, err = NewExternalTileSet(path, gr.MDx, bytes.NewReader(gf), palette); err != nil {
+				t.Fatal(err)
+			}
+			ts := ets.TileSet()
+			if ts.Name != "GraphicInfo" || ts.TileCount != 4 {
+				t.Errorf("TileSet() name = %q, tile count = %d", ts.Name, ts.TileCount)
+			}
+			for _, tile := range ts.Tiles {
+				info := gr.MDx.First(int32(tile.ID + 1)).Info
+				if tile.ImageWidth != int(info.Width) || tile.ImageHeight != int(info.Height) {
+					t.Errorf("tile %d: image size = %dx%d, want %dx%d", tile.ID, tile.ImageWidth, tile.ImageHeight, info.Width, info.Height)
+				}
+			}
+
+			images, _ := filepath.Glob(filepath.Join(dir, "tiles", "GraphicInfo", "*.png"))
+			sort.Strings(images)
+			wantImages := lo.Map([]string{"1.png", "2.png", "3.png", "4.png"}, func(name string, _ int) string {
+				return filepath.Join(dir, "tiles", "GraphicInfo", name)
+			})
+			if diff := cmp.Diff(wantImages, images); diff != "" {
+				t.Errorf("images mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExternalTileSet_add_Failed(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.RGBA{R: 0xff, A: 0xff}}
+	gi, gf := writeGraphics(t, 2, palette)
+
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the graphic file is truncated, so no image can be rendered
+	dir := t.TempDir()
+	ets, err := NewExternalTileSet(filepath.Join(dir, "GraphicInfo.tsj"), gr.MDx, bytes.NewReader(gf[:8]), palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMap(2, 1)
+	m.Ground = []uint16{1, 2}
+	if _, err = m.TiledMapExternal(ets, dir); err == nil {
+		t.Fatal("expected error of truncated graphic file")
+	}
+	if ts := ets.TileSet(); ts.TileCount != 0 {
+		t.Errorf("TileSet() tile count = %d, want 0 after failed rendering", ts.TileCount)
+	}
+	if images, _ := filepath.Glob(filepath.Join(dir, "GraphicInfo", "*")); len(images) != 0 {
+		t.Errorf("expected no partial images, got %v", images)
+	}
+
+	// the failed tiles are rendered again for the next map
+	ets.GraphicFile = bytes.NewReader(gf)
+	if _, err = m.TiledMapExternal(ets, dir); err != nil {
+		t.Fatal(err)
+	}
+	if ts := ets.TileSet(); ts.TileCount != 2 {
+		t.Errorf("TileSet() tile count = %d, want 2", ts.TileCount)
+	}
+	for _, name := range []string{"1.png", "2.png"} {
+		if _, err = os.Stat(filepath.Join(dir, "GraphicInfo", name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"xgtool/internal/mat"
+	"xgtool/internal/tmx"
+)
+
+// ErrInvalidGrid is returned when the size of walkability data doesn't match its width and height.
+var ErrInvalidGrid = errors.New("invalid grid")
+
+// Walkability is the walkability grid of a map, cell (col, row) is walkable if the bit at row*W+col is set.
+type Walkability struct {
+	W, H int
+	bits []uint64
+}
+
+// NewWalkability creates a grid of w*h cells, all cells are blocked.
+func NewWalkability(w, h int) Walkability {
+	return Walkability{W: w, H: h, bits: make([]uint64, (w*h+63)/64)}
+}
+
+// Walkability computes the walkability grid of m with graphics indexed by MapID, such as GraphicResource.MDx.
+//
+// A cell is walkable if its ground graphic exists and GraphicInfo.Access is not 0. Then the footprint of each object
+// with Access 0 is blocked, the footprint is GridW columns from the cell of object to the upper right, and GridH rows
+// from the cell to the upper left (see CellCenter).
+func (m Map) Walkability(index GraphicIndex) (wk Walkability) {
+	w, h := int(m.Header.Width), int(m.Header.Height)
+	wk = NewWalkability(w, h)
+
+	for i, t := range m.Ground {
+		if g := index.First(int32(t)); t != 0 && g != nil && g.Info.Access != 0 {
+			wk.Set(i%w, i/w, true)
+		}
+	}
+
+	for i, t := range m.Object {
+		g := index.First(int32(t))
+		if t == 0 || g == nil || g.Info.Access != 0 {
+			continue
+		}
+
+		col, row := i%w, i/w
+		for x := 0; x < max(int(g.Info.GridW), 1); x++ {
+			for y := 0; y < max(int(g.Info.GridH), 1); y++ {
+				wk.Set(col+x, row-y, false)
+			}
+		}
+	}
+
+	return
+}
+
+// Walkable reports whether cell (col, row) is walkable, cells out of grid are not walkable.
+func (wk Walkability) Walkable(col, row int) bool {
+	if col < 0 || col >= wk.W || row < 0 || row >= wk.H {
+		return false
+	}
+	i := row*wk.W + col
+
+	return wk.bits[i/64]&(1<<(i%64)) != 0
+}
+
+// Set sets the walkability of cell (col, row), cells out of grid are ignored.
+func (wk Walkability) Set(col, row int, walkable bool) {
+	if col < 0 || col >= wk.W || row < 0 || row >= wk.H {
+		return
+	}
+	i := row*wk.W + col
+
+	if walkable {
+		wk.bits[i/64] |= 1 << (i % 64)
+	} else {
+		wk.bits[i/64] &^= 1 << (i % 64)
+	}
+}
+
+// Bools returns the grid as []bool, in the same order as Map.Ground.
+func (wk Walkability) Bools() (b []bool) {
+	b = make([]bool, wk.W*wk.H)
+	for i := range b {
+		b[i] = wk.Walkable(i%wk.W, i/wk.W)
+	}
+
+	return
+}
+
+// walkabilityJSON is the JSON format of Walkability, Data is row by row, 1 for walkable and 0 for blocked.
+type walkabilityJSON struct {
+	Width  int   `json:"width"`
+	Height int   `json:"height"`
+	Data   []int `json:"data"`
+}
+
+// MarshalJSON encodes the grid as {"width": W, "height": H, "data": [1, 0, ...]}.
+func (wk Walkability) MarshalJSON() ([]byte, error) {
+	v := walkabilityJSON{Width: wk.W, Height: wk.H, Data: make([]int, wk.W*wk.H)}
+	for i, b := range wk.Bools() {
+		if b {
+			v.Data[i] = 1
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes the grid from the format of MarshalJSON.
+func (wk *Walkability) UnmarshalJSON(b []byte) (err error) {
+	var v walkabilityJSON
+	if err = json.Unmarshal(b, &v); err != nil {
+		return
+	}
+	if len(v.Data) != v.Width*v.Height {
+		return fmt.Errorf("%w: len(data)=%d, width=%d, height=%d", ErrInvalidGrid, len(v.Data), v.Width, v.Height)
+	}
+
+	*wk = NewWalkability(v.Width, v.Height)
+	for i, d := range v.Data {
+		wk.Set(i%v.Width, i/v.Width, d != 0)
+	}
+
+	return
+}
+
+// Mask returns the grid as an image of W*H pixels, white for walkable and black for blocked.
+func (wk Walkability) Mask() (img *image.Gray) {
+	img = image.NewGray(image.Rect(0, 0, wk.W, wk.H))
+	for i, b := range wk.Bools() {
+		if b {
+			img.Pix[i] = 0xff
+		}
+	}
+
+	return
+}
+
+// buildCollisionLayer creates the tile layer of blocked cells with gid, rotated -90 degrees like the ground layer.
+func (wk Walkability) buildCollisionLayer(gid int) (layer tmx.Layer, err error) {
+	layer = tmx.NewTileLayer("collision", 4, wk.H, wk.W)
+	layer.Opacity = 0.5
+
+	tiles := make([]int, wk.W*wk.H)
+	for i, b := range wk.Bools() {
+		if !b {
+			tiles[i] = gid
+		}
+	}
+
+	var matrix mat.Matrix
+	if matrix, err = mat.NewMatrix(tiles, wk.W, wk.H); err != nil {
+		return
+	}
+	for _, t := range matrix.Rotate().Data {
+		layer.Data = append(layer.Data, uint(t))
+	}
+
+	return
+}
+
+// CollisionImage is the file name of collision tile image, which is shared by the converted maps.
+const CollisionImage = "collision.png"
+
+// WriteCollisionTile writes the image of collision tile to name in PNG.
+func WriteCollisionTile(name string) (err error) {
+	var out *os.File
+	if out, err = os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644); err != nil {
+		return
+	}
+
+	if err = png.Encode(out, collisionTile()); err != nil {
+		_ = out.Close()
+		return
+	}
+
+	return out.Close()
+}
+
+// collisionTile returns the image of collision tile, a translucent red diamond of a cell.
+func collisionTile() (img *image.NRGBA) {
+	img = image.NewNRGBA(image.Rect(0, 0, TileWidth, TileHeight))
+	c := color.NRGBA{R: 0xff, A: 0x80}
+
+	for y := 0; y < TileHeight; y++ {
+		// the half width of diamond at row y
+		hw := float64(TileWidth) / 2 * (1 - math.Abs(float64(2*y+1-TileHeight))/TileHeight)
+		for x := 0; x < TileWidth; x++ {
+			if math.Abs(float64(2*x+1-TileWidth))/2 <= hw {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+
+	return
+}
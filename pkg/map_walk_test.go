@@ -0,0 +1,90 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"image"
+	"testing"
+	"xgtool/internal/tmx"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMap_Walkability(t *testing.T) {
+	index := GraphicIndex{
+		1: {{Info: GraphicInfo{MapID: 1, Access: 1}}},                     // walkable ground
+		2: {{Info: GraphicInfo{MapID: 2, Access: 0}}},                     // water
+		3: {{Info: GraphicInfo{MapID: 3, Access: 0, GridW: 2, GridH: 2}}}, // house
+		4: {{Info: GraphicInfo{MapID: 4, Access: 1, GridW: 1, GridH: 1}}}, // carpet
+		5: {{Info: GraphicInfo{MapID: 5, Access: 0, GridW: 2, GridH: 1}}}, // fence, partly out of map
+	}
+	m := Map{
+		Header: mapHeader{Width: 4, Height: 4},
+		Ground: []uint16{
+			2, 1, 1, 1,
+			1, 1, 1, 1,
+			1, 1, 1, 1,
+			1, 1, 1, 0,
+		},
+		Object: []uint16{
+			0, 0, 0, 4,
+			0, 0, 0, 5,
+			0, 3, 0, 0,
+			0, 0, 0, 9, // not in index
+		},
+	}
+
+	wk := m.Walkability(index)
+	expected := []bool{
+		false, true, true, true,
+		true, false, false, false,
+		true, false, false, true,
+		true, true, true, false,
+	}
+	if diff := cmp.Diff(expected, wk.Bools()); diff != "" {
+		t.Errorf("Walkability() mismatch (-want +got):\n%s", diff)
+	}
+	if wk.Walkable(-1, 0) || wk.Walkable(4, 0) {
+		t.Error("expected cells out of grid are not walkable")
+	}
+
+	mask := wk.Mask()
+	if mask.Bounds() != image.Rect(0, 0, 4, 4) || mask.GrayAt(1, 0).Y != 0xff || mask.GrayAt(0, 0).Y != 0 {
+		t.Errorf("unexpected mask: %v", mask.Pix)
+	}
+
+	layer, err := wk.buildCollisionLayer(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rotated -90 degrees, the last column becomes the first row
+	if diff := cmp.Diff(tmx.Data{0, 7, 0, 7}, layer.Data[:4]); diff != "" {
+		t.Errorf("buildCollisionLayer() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWalkability_JSON(t *testing.T) {
+	wk := NewWalkability(3, 2)
+	wk.Set(0, 0, true)
+	wk.Set(2, 1, true)
+
+	b, err := json.Marshal(wk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"width":3,"height":2,"data":[1,0,0,0,0,1]}` {
+		t.Errorf("unexpected json: %s", b)
+	}
+
+	var got Walkability
+	if err = json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wk.Bools(), got.Bools()); diff != "" {
+		t.Errorf("json round trip mismatch (-want +got):\n%s", diff)
+	}
+
+	if err = json.Unmarshal([]byte(`{"width":3,"height":2,"data":[1]}`), &got); !errors.Is(err, ErrInvalidGrid) {
+		t.Errorf("expected error: %v, got %v", ErrInvalidGrid, err)
+	}
+}
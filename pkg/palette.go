@@ -3,10 +3,15 @@ package pkg
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"image"
 	"image/color"
 	"io"
 )
 
+// ErrTooManyColors is returned when an image can't be represented by a 256 colors palette.
+var ErrTooManyColors = errors.New("too many colors")
+
 // CGPSize reads bytes from CGP file, (256 colors - 32 default colors) * 3 bytes per color.
 const CGPSize = (256 - 32) * 3
 
@@ -87,3 +92,119 @@ func NewPaletteFromBytes(b []byte) (p color.Palette, err error) {
 
 	return
 }
+
+// PaletteToBytes converts palette to bytes (BGR order), the reverse of NewPaletteFromBytes.
+//
+// Transparent colors are written as RGB(0, 0, 0).
+func PaletteToBytes(p color.Palette) (b []byte) {
+	b = make([]byte, 0, len(p)*3)
+
+	for _, c := range p {
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		if rgba.A == 0 {
+			b = append(b, 0, 0, 0)
+			continue
+		}
+
+		b = append(b, rgba.B, rgba.G, rgba.R)
+	}
+
+	return
+}
+
+// NewPaletteFromImage makes a palette from the colors of img, it can be embedded into a graphic with version >= 2.
+//
+// The first color is always transparent. Opaque colors which would be read as transparent by NewPaletteFromBytes are
+// adjusted by 1 in a channel, so they stay opaque. ErrTooManyColors is returned when img has more than 256 colors.
+func NewPaletteFromImage(img image.Image) (p color.Palette, err error) {
+	p = color.Palette{color.Transparent}
+	seen := make(map[color.RGBA]bool)
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c, ok := opaque(img.At(x, y))
+			if !ok {
+				continue
+			}
+			if c = storable(c); seen[c] {
+				continue
+			}
+			seen[c] = true
+
+			if len(p) == 256 {
+				return nil, fmt.Errorf("%w: more than 256 colors", ErrTooManyColors)
+			}
+			p = append(p, c)
+		}
+	}
+
+	return
+}
+
+// opaque converts c to an opaque color, ok is false if c is transparent (alpha < 50%).
+func opaque(c color.Color) (rgba color.RGBA, ok bool) {
+	r, g, b, a := c.RGBA()
+	if a < 0x8000 {
+		return
+	}
+
+	// un-premultiply the color
+	return color.RGBA{R: uint8(r * 0xff / a), G: uint8(g * 0xff / a), B: uint8(b * 0xff / a), A: 0xff}, true
+}
+
+// storable adjusts the opaque color which would be read as transparent by NewPaletteFromBytes.
+func storable(c color.RGBA) color.RGBA {
+	switch {
+	case c.R == 0 && c.G == 0 && (c.B == 0 || c.B == 0xff):
+		c.G = 1
+	case c.R == 0 && c.G == 0xff && c.B == 0:
+		c.R = 1
+	case c.R == 0xff && c.G == 0 && c.B == 0:
+		c.G = 1
+	}
+
+	return c
+}
+
+// paletteMapper maps colors to the nearest index of palette, with caching.
+type paletteMapper struct {
+	p           color.Palette
+	transparent byte
+	cache       map[color.RGBA]byte
+}
+
+func newPaletteMapper(p color.Palette) (m paletteMapper) {
+	m.p = p
+	m.transparent = transparentIndex(p)
+	m.cache = make(map[color.RGBA]byte)
+
+	return
+}
+
+// transparentIndex returns the index of first transparent color in p, or 0 if not found.
+func transparentIndex(p color.Palette) byte {
+	for i, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return byte(i)
+		}
+	}
+
+	return 0
+}
+
+func (m paletteMapper) index(c color.Color) byte {
+	rgba, ok := opaque(c)
+	if !ok {
+		return m.transparent
+	}
+
+	if i, ok := m.cache[rgba]; ok {
+		return i
+	}
+
+	i := byte(m.p.Index(rgba))
+	m.cache[rgba] = i
+
+	return i
+}
@@ -0,0 +1,130 @@
+package pkg
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+// ErrPaletteNotFound is returned when a PaletteResolver can't find the palette of an anime.
+var ErrPaletteNotFound = errors.New("palette not found")
+
+// PaletteResolver finds the palette to render the frames of an anime.
+//
+// Graphics with an embedded palette (version >= 2) are always rendered with their own palette, the resolved palette is
+// used for the others.
+type PaletteResolver interface {
+	// Resolve returns the palette of aidx, or an error wrapping ErrPaletteNotFound if there is none.
+	Resolve(aidx AnimeIndex) (p color.Palette, err error)
+}
+
+// CGPPaletteResolver resolves every anime to the same palette, which is usually made by NewPaletteFromCGP.
+type CGPPaletteResolver struct {
+	Palette color.Palette
+}
+
+// Resolve returns r.Palette, the anime is not used.
+func (r CGPPaletteResolver) Resolve(aidx AnimeIndex) (p color.Palette, err error) {
+	if len(r.Palette) == 0 {
+		return nil, fmt.Errorf("%w: anime=%d, cgp palette is empty", ErrPaletteNotFound, aidx.Info.ID)
+	}
+
+	return r.Palette, nil
+}
+
+// EmbeddedPaletteResolver resolves the palette embedded in the frames of anime, the first graphic with version >= 2 wins.
+//
+// The animes of aidx must be loaded, only the graphic headers are read from GraphicFile until a palette is found.
+type EmbeddedPaletteResolver struct {
+	GraphicFile io.ReaderAt
+}
+
+// Resolve returns the palette of the first frame graphic which has one.
+func (r EmbeddedPaletteResolver) Resolve(aidx AnimeIndex) (p color.Palette, err error) {
+	checked := make(map[int32]bool)
+	for _, a := range aidx.Sorted() {
+		for _, f := range a.Frames {
+			if f.Graphic == nil || checked[f.Graphic.Info.ID] {
+				continue
+			}
+			checked[f.Graphic.Info.ID] = true
+
+			var h GraphicHeader
+			if h, err = f.Graphic.Info.readHeaderAt(r.GraphicFile); err != nil {
+				return
+			}
+			if h.Version < 2 {
+				continue
+			}
+
+			var g *Graphic
+			if g, err = f.Graphic.Info.LoadGraphicAt(r.GraphicFile); err != nil {
+				return
+			}
+			if len(g.PaletteData) > 0 {
+				return g.PaletteData, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: anime=%d, no embedded palette", ErrPaletteNotFound, aidx.Info.ID)
+}
+
+// AnimePaletteResolver resolves the palette of the graphic whose MapID is the anime ID.
+//
+// It's the convention of 3.0 and later versions, such as GraphicInfoV3 for AnimeV3 and GraphicInfo_Joy_EX for
+// Anime_Joy_EX, the palette graphic is loaded from GraphicFile.
+type AnimePaletteResolver struct {
+	GraphicResource GraphicResource
+	GraphicFile     io.ReaderAt
+}
+
+// Resolve returns the palette of the palette graphic of aidx.
+func (r AnimePaletteResolver) Resolve(aidx AnimeIndex) (p color.Palette, err error) {
+	id := int32(aidx.Info.ID)
+	if len(r.GraphicResource.MDx.Find(id)) == 0 {
+		return nil, fmt.Errorf("%w: anime=%d, no palette graphic", ErrPaletteNotFound, aidx.Info.ID)
+	}
+
+	var gs []*Graphic
+	if gs, err = r.GraphicResource.MDx.LoadAt(id, r.GraphicFile); err != nil {
+		return
+	}
+	if len(gs[0].PaletteData) == 0 {
+		return nil, fmt.Errorf("%w: anime=%d, palette graphic=%+v has no palette", ErrPaletteNotFound, aidx.Info.ID, gs[0].Info)
+	}
+
+	return gs[0].PaletteData, nil
+}
+
+// PaletteResolverChain tries the resolvers in order, and returns the first palette found.
+//
+// Errors other than ErrPaletteNotFound stop the chain.
+type PaletteResolverChain []PaletteResolver
+
+// Resolve returns the palette from the first resolver which finds one.
+func (c PaletteResolverChain) Resolve(aidx AnimeIndex) (p color.Palette, err error) {
+	for _, r := range c {
+		if p, err = r.Resolve(aidx); err == nil {
+			return
+		} else if !errors.Is(err, ErrPaletteNotFound) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("%w: anime=%d", ErrPaletteNotFound, aidx.Info.ID)
+}
+
+// readHeaderAt reads only the graphic header from graphic file.
+func (gi GraphicInfo) readHeaderAt(gf io.ReaderAt) (h GraphicHeader, err error) {
+	if err = binary.Read(io.NewSectionReader(gf, int64(gi.Addr), int64(gi.Len)), binary.LittleEndian, &h); err != nil {
+		return
+	}
+	if !h.Valid() {
+		return h, fmt.Errorf("%w: info=%+v, header=%+v", ErrInvalidMagic, gi, h)
+	}
+
+	return
+}
@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// withPalettes edits the graphics of writeGraphics: 1 without palette, 2 with embedded palette, 3 is the palette
+// graphic of anime 100000.
+func withPalettes(embedded, anime color.Palette) func(g *Graphic) {
+	return func(g *Graphic) {
+		switch g.Info.ID {
+		case 2:
+			g.Header.Version, g.PaletteData = 3, embedded
+		case 3:
+			g.Info.MapID, g.Header.Version, g.PaletteData = 100000, 2, anime
+		}
+	}
+}
+
+func TestPaletteResolver(t *testing.T) {
+	cgp := color.Palette{color.Transparent, color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff}}
+	embedded := color.Palette{color.Transparent, color.RGBA{R: 0x40, G: 0x50, B: 0x60, A: 0xff}}
+	anime := color.Palette{color.Transparent, color.RGBA{R: 0x70, G: 0x80, B: 0x90, A: 0xff}}
+
+	gi, gf := writeGraphics(t, 3, cgp, withPalettes(embedded, anime))
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aidx := func(id AnimeID, graphics ...int32) AnimeIndex {
+		a := Anime{}
+		for _, g := range graphics {
+			a.Frames = append(a.Frames, AnimeFrame{Data: AnimeFrameData{GraphicID: g}, Graphic: gr.IDx.First(g)})
+		}
+
+		return AnimeIndex{Info: AnimeInfo{ID: id}, Animes: map[ActionID][]Anime{0: {a}}}
+	}
+
+	byAnime := AnimePaletteResolver{GraphicResource: gr, GraphicFile: bytes.NewReader(gf)}
+	byEmbedded := EmbeddedPaletteResolver{GraphicFile: bytes.NewReader(gf)}
+	byCGP := CGPPaletteResolver{Palette: cgp}
+
+	testcases := []struct {
+		name     string
+		resolver PaletteResolver
+		aidx     AnimeIndex
+		expected color.Palette
+	}{
+		{"cgp", byCGP, aidx(1, 1), cgp},
+		{"cgp empty", CGPPaletteResolver{}, aidx(1, 1), nil},
+		{"embedded", byEmbedded, aidx(1, 1, 2), embedded},
+		{"embedded not found", byEmbedded, aidx(1, 1), nil},
+		{"anime", byAnime, aidx(100000, 1), anime},
+		{"anime not found", byAnime, aidx(1, 1), nil},
+		{"chain anime first", PaletteResolverChain{byAnime, byCGP, byEmbedded}, aidx(100000, 2), anime},
+		{"chain fallback to cgp", PaletteResolverChain{byAnime, byCGP, byEmbedded}, aidx(1, 2), cgp},
+		{"chain fallback to embedded", PaletteResolverChain{byAnime, byEmbedded}, aidx(1, 1, 2), embedded},
+		{"chain not found", PaletteResolverChain{byAnime, byEmbedded}, aidx(1, 1), nil},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := tc.resolver.Resolve(tc.aidx)
+			if tc.expected == nil {
+				if !errors.Is(err, ErrPaletteNotFound) {
+					t.Fatalf("expected error: %v, got %v", ErrPaletteNotFound, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.expected, p); diff != "" {
+				t.Errorf("Resolve() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPaletteResolverChain_Error(t *testing.T) {
+	p := color.Palette{color.RGBA{}}
+	gi, gf := writeGraphics(t, 3, p, withPalettes(p, p))
+	gr, err := NewGraphicResource(bytes.NewReader(gi))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the graphic file is truncated, so reading the palette graphic fails with an error other than ErrPaletteNotFound
+	c := PaletteResolverChain{
+		AnimePaletteResolver{GraphicResource: gr, GraphicFile: bytes.NewReader(gf[:len(gf)-1])},
+		CGPPaletteResolver{Palette: color.Palette{color.RGBA{}}},
+	}
+
+	if _, err := c.Resolve(AnimeIndex{Info: AnimeInfo{ID: 100000}}); err == nil || errors.Is(err, ErrPaletteNotFound) {
+		t.Errorf("expected read error, got %v", err)
+	}
+}
@@ -1,10 +1,15 @@
 package pkg
 
 import (
+	"bytes"
 	"errors"
+	"image"
+	"image/color"
 	"io"
 	"os"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestMakePaletteFromCGP(t *testing.T) {
@@ -62,3 +67,54 @@ func TestMakePaletteFromBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestPaletteToBytes(t *testing.T) {
+	data := []byte{0x00, 0x00, 0x00, 0x30, 0x20, 0x10, 0xff, 0xff, 0xff}
+
+	p, err := NewPaletteFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := PaletteToBytes(p); !bytes.Equal(b, data) {
+		t.Errorf("PaletteToBytes() = %v, want %v", b, data)
+	}
+}
+
+func TestNewPaletteFromImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff})
+	img.Set(1, 0, color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff})
+	img.Set(0, 1, color.RGBA{A: 0xff}) // opaque black, adjusted to stay opaque
+
+	p, err := NewPaletteFromImage(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := color.Palette{
+		color.Transparent,
+		color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff},
+		color.RGBA{G: 0x01, A: 0xff},
+	}
+	if diff := cmp.Diff(expect, p); diff != "" {
+		t.Errorf("palette mismatch (-want +got):\n%s", diff)
+	}
+
+	// the palette survives the bytes round-trip
+	back, _ := NewPaletteFromBytes(PaletteToBytes(p))
+	if diff := cmp.Diff(expect, back); diff != "" {
+		t.Errorf("palette bytes mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewPaletteFromImage_TooManyColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 2))
+	for i := 0; i < 512; i++ {
+		img.Set(i%256, i/256, color.RGBA{R: byte(i), G: byte(i / 256), B: 0x80, A: 0xff})
+	}
+
+	if _, err := NewPaletteFromImage(img); !errors.Is(err, ErrTooManyColors) {
+		t.Errorf("expected error: %v, got: %v", ErrTooManyColors, err)
+	}
+}
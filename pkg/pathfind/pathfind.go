@@ -0,0 +1,182 @@
+// Package pathfind finds paths over the CrossGate tile grid with A*.
+package pathfind
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"image"
+)
+
+var (
+	// ErrBlocked is returned when the start or the goal is not walkable.
+	ErrBlocked = errors.New("blocked")
+	// ErrNoPath is returned when the goal can't be reached from the start.
+	ErrNoPath = errors.New("no path")
+)
+
+// Grid reports whether a cell is walkable, cells out of grid should not be walkable.
+//
+// pkg.Walkability is a Grid, X is column and Y is row.
+type Grid interface {
+	Walkable(x, y int) bool
+}
+
+// Diagonal is the rule of diagonal moves.
+type Diagonal int
+
+const (
+	// NoCorner allows a diagonal move if both cells next to the corner are walkable, it's the default.
+	NoCorner Diagonal = iota
+	// OneCorner allows a diagonal move if one of the cells next to the corner is walkable.
+	OneCorner
+	// Always allows every diagonal move to a walkable cell, even through two blocked cells.
+	Always
+	// Never allows only the 4 orthogonal moves.
+	Never
+)
+
+// the costs of moves, a diagonal move is about sqrt(2) times of an orthogonal move
+const (
+	orthogonal = 10
+	diagonal   = 14
+)
+
+// directions are the 8 moves, the orthogonal moves first.
+var directions = [...]image.Point{
+	{X: 1}, {X: -1}, {Y: 1}, {Y: -1},
+	{X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1},
+}
+
+// Find returns the shortest path from "from" to "to" on g, both ends are included.
+func Find(g Grid, from, to image.Point, d Diagonal) (path []image.Point, err error) {
+	if !g.Walkable(from.X, from.Y) {
+		return nil, fmt.Errorf("%w: from=%v", ErrBlocked, from)
+	}
+	if !g.Walkable(to.X, to.Y) {
+		return nil, fmt.Errorf("%w: to=%v", ErrBlocked, to)
+	}
+
+	cost := map[image.Point]int{from: 0}
+	prev := make(map[image.Point]image.Point)
+	open := &queue{{pt: from, f: heuristic(from, to, d)}}
+
+	for open.Len() > 0 {
+		n := heap.Pop(open).(node)
+		if n.pt == to {
+			return trace(prev, from, to), nil
+		}
+		if n.g > cost[n.pt] {
+			// a shorter path to this cell has been found
+			continue
+		}
+
+		for _, dir := range directions {
+			next := n.pt.Add(dir)
+			if !movable(g, n.pt, dir, d) {
+				continue
+			}
+
+			c := n.g + orthogonal
+			if dir.X != 0 && dir.Y != 0 {
+				c = n.g + diagonal
+			}
+			if old, ok := cost[next]; ok && old <= c {
+				continue
+			}
+
+			cost[next] = c
+			prev[next] = n.pt
+			heap.Push(open, node{pt: next, g: c, f: c + heuristic(next, to, d)})
+		}
+	}
+
+	return nil, fmt.Errorf("%w: from=%v, to=%v", ErrNoPath, from, to)
+}
+
+// movable reports whether it can move from pt in dir by rule d.
+func movable(g Grid, pt, dir image.Point, d Diagonal) bool {
+	next := pt.Add(dir)
+	if !g.Walkable(next.X, next.Y) {
+		return false
+	}
+	if dir.X == 0 || dir.Y == 0 {
+		return true
+	}
+
+	a, b := g.Walkable(pt.X+dir.X, pt.Y), g.Walkable(pt.X, pt.Y+dir.Y)
+	switch d {
+	case NoCorner:
+		return a && b
+	case OneCorner:
+		return a || b
+	case Always:
+		return true
+	default:
+		return false
+	}
+}
+
+// heuristic returns the octile distance, or the manhattan distance if diagonal moves are not allowed.
+func heuristic(a, b image.Point, d Diagonal) int {
+	dx, dy := abs(a.X-b.X), abs(a.Y-b.Y)
+	if d == Never {
+		return (dx + dy) * orthogonal
+	}
+
+	return orthogonal*(dx+dy) + (diagonal-2*orthogonal)*min(dx, dy)
+}
+
+func trace(prev map[image.Point]image.Point, from, to image.Point) (path []image.Point) {
+	for pt := to; pt != from; pt = prev[pt] {
+		path = append(path, pt)
+	}
+	path = append(path, from)
+
+	// reverse to start from "from"
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+// node is a cell in open set, g is the cost from start, and f is g plus the heuristic to goal.
+type node struct {
+	pt   image.Point
+	g, f int
+}
+
+// queue is a priority queue of nodes ordered by f, it implements heap.Interface.
+type queue []node
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+
+	// prefer the node closer to goal
+	return q[i].g > q[j].g
+}
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x any) { *q = append(*q, x.(node)) }
+
+func (q *queue) Pop() (x any) {
+	old := *q
+	x = old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return
+}
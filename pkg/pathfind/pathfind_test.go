@@ -0,0 +1,165 @@
+package pathfind
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// grid is a Grid from rows of text, '#' is blocked.
+type grid []string
+
+func (g grid) Walkable(x, y int) bool {
+	return y >= 0 && y < len(g) && x >= 0 && x < len(g[y]) && g[y][x] != '#'
+}
+
+// cost returns the cost of path.
+func cost(path []image.Point) (c int) {
+	for i := 1; i < len(path); i++ {
+		if d := path[i].Sub(path[i-1]); d.X != 0 && d.Y != 0 {
+			c += diagonal
+		} else {
+			c += orthogonal
+		}
+	}
+
+	return
+}
+
+func TestFind(t *testing.T) {
+	open := grid{
+		"....",
+		"....",
+		"....",
+	}
+	wall := grid{
+		".#..",
+		".#..",
+		"....",
+	}
+	corner := grid{
+		".#",
+		"#.",
+	}
+	halfCorner := grid{
+		"..",
+		"#.",
+	}
+
+	tests := []struct {
+		name     string
+		g        grid
+		from, to image.Point
+		d        Diagonal
+		want     []image.Point
+		wantCost int
+		wantErr  error
+	}{
+		{
+			name: "same", g: open, from: image.Pt(1, 1), to: image.Pt(1, 1),
+			want: []image.Point{{1, 1}},
+		},
+		{
+			name: "diagonal", g: open, from: image.Pt(0, 0), to: image.Pt(2, 2),
+			want:     []image.Point{{0, 0}, {1, 1}, {2, 2}},
+			wantCost: 2 * diagonal,
+		},
+		{
+			name: "never diagonal", g: open, from: image.Pt(0, 0), to: image.Pt(2, 2), d: Never,
+			wantCost: 4 * orthogonal,
+		},
+		{
+			name: "around wall", g: wall, from: image.Pt(0, 0), to: image.Pt(2, 0),
+			// no corner cutting around (1, 1), so it's all orthogonal
+			wantCost: 6 * orthogonal,
+		},
+		{
+			name: "around wall with one corner", g: wall, from: image.Pt(0, 0), to: image.Pt(2, 0), d: OneCorner,
+			wantCost: 2*orthogonal + 2*diagonal,
+		},
+		{
+			name: "no corner", g: corner, from: image.Pt(0, 0), to: image.Pt(1, 1),
+			wantErr: ErrNoPath,
+		},
+		{
+			name: "always", g: corner, from: image.Pt(0, 0), to: image.Pt(1, 1), d: Always,
+			want:     []image.Point{{0, 0}, {1, 1}},
+			wantCost: diagonal,
+		},
+		{
+			name: "half corner", g: halfCorner, from: image.Pt(0, 0), to: image.Pt(1, 1),
+			want:     []image.Point{{0, 0}, {1, 0}, {1, 1}},
+			wantCost: 2 * orthogonal,
+		},
+		{
+			name: "half corner with one corner", g: halfCorner, from: image.Pt(0, 0), to: image.Pt(1, 1), d: OneCorner,
+			want:     []image.Point{{0, 0}, {1, 1}},
+			wantCost: diagonal,
+		},
+		{
+			name: "blocked goal", g: wall, from: image.Pt(0, 0), to: image.Pt(1, 0),
+			wantErr: ErrBlocked,
+		},
+		{
+			name: "out of grid", g: open, from: image.Pt(-1, 0), to: image.Pt(1, 0),
+			wantErr: ErrBlocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Find(tt.g, tt.from, tt.to, tt.d)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Find() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if tt.want != nil {
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("Find() mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if c := cost(got); c != tt.wantCost {
+				t.Errorf("cost(Find()) = %d, want %d", c, tt.wantCost)
+			}
+			if got[0] != tt.from || got[len(got)-1] != tt.to {
+				t.Errorf("Find() = %v, want from %v to %v", got, tt.from, tt.to)
+			}
+			for i := 1; i < len(got); i++ {
+				if !movable(tt.g, got[i-1], got[i].Sub(got[i-1]), tt.d) {
+					t.Errorf("Find() = %v, can't move from %v to %v", got, got[i-1], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFind_Large(t *testing.T) {
+	// a maze of walls with a gap at alternate ends
+	rows := make(grid, 0, 41)
+	for y := 0; y < 41; y++ {
+		switch {
+		case y%4 == 1:
+			rows = append(rows, strings.Repeat("#", 40)+".")
+		case y%4 == 3:
+			rows = append(rows, "."+strings.Repeat("#", 40))
+		default:
+			rows = append(rows, strings.Repeat(".", 41))
+		}
+	}
+
+	got, err := Find(rows, image.Pt(0, 0), image.Pt(0, 40), NoCorner)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	// 10 rounds of crossing the map and back, each round is 2 walks of 40 cells and 4 steps down
+	if c, want := cost(got), 10*(2*40+4)*orthogonal; c != want {
+		t.Errorf("cost(Find()) = %d, want %d", c, want)
+	}
+}